@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/roelofruis/mahjong-learn/mahjong"
@@ -8,27 +9,57 @@ import (
 	"github.com/roelofruis/mahjong-learn/state"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func (s *Server) handleIndex(_ *http.Request) *Response {
 	return &Response{
 		StatusCode: http.StatusOK,
 		Data: &struct {
-			Message      string `json:"message"`
-			Version      string `json:"version"`
-			GamesStarted int    `json:"games_started"`
-			NewGame      string `json:"new_game"`
+			Message      string   `json:"message"`
+			Version      string   `json:"version"`
+			GamesStarted int      `json:"games_started"`
+			NewGame      string   `json:"new_game"`
+			Rulesets     []string `json:"rulesets"`
 		}{
 			Message:      "Mahjong Game API",
 			Version:      "0.1",
 			GamesStarted: int(*s.Games.lastIndex),
 			NewGame:      fmt.Sprintf("%s/new", s.GetDomain(true)),
+			Rulesets:     mahjong.RulesetNames(),
 		},
 	}
 }
 
 func (s *Server) handleNew(r *http.Request) *Response {
-	id, err := s.Games.StartNew()
+	rulesetName := r.FormValue("ruleset")
+	if rulesetName == "" {
+		rulesetName = "classical"
+	}
+	rules, err := mahjong.RulesetByName(rulesetName)
+	if err == nil {
+		err = applyMatchOptions(r, &rules)
+	}
+	if err != nil {
+		return &Response{
+			StatusCode: http.StatusBadRequest,
+			Error:      err,
+		}
+	}
+
+	seed := time.Now().UnixNano()
+	if value := r.FormValue("seed"); value != "" {
+		seed, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return &Response{
+				StatusCode: http.StatusBadRequest,
+				Error:      fmt.Errorf("invalid seed value [%s]", value),
+			}
+		}
+	}
+
+	id, err := s.Games.StartNew(rules, seed)
 	if err != nil {
 		return &Response{
 			StatusCode: http.StatusInternalServerError,
@@ -41,15 +72,55 @@ func (s *Server) handleNew(r *http.Request) *Response {
 		Data: &struct {
 			Message  string `json:"message"`
 			Id       uint64 `json:"id"`
+			Seed     int64  `json:"seed"`
 			Location string `json:"location"`
 		}{
 			Message:  "Game created",
 			Id:       id,
+			Seed:     seed,
 			Location: fmt.Sprintf("%s/game/%d", s.GetDomain(true), id),
 		},
 	}
 }
 
+// Override the match length and end conditions of the ruleset with the ones given in the request, if any,
+// and switch on the requested house rules.
+func applyMatchOptions(r *http.Request, rules *mahjong.Ruleset) error {
+	if name := r.FormValue("length"); name != "" {
+		length, err := mahjong.MatchLengthByName(name)
+		if err != nil {
+			return err
+		}
+		rules.Length = length
+	}
+
+	if value := r.FormValue("bust"); value != "" {
+		bust, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid bust value [%s]", value)
+		}
+		rules.BustEnds = bust
+	}
+
+	if value := r.FormValue("overtime"); value != "" {
+		target, err := strconv.Atoi(value)
+		if err != nil || target < 0 {
+			return fmt.Errorf("invalid overtime value [%s]", value)
+		}
+		rules.OvertimeTarget = target
+	}
+
+	if value := r.FormValue("house"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			if err := rules.House.Enable(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *Server) handleDisplayGame(r *http.Request, game *mahjong.Game, _ uint64) *Response {
 	return &Response{
 		StatusCode: http.StatusOK,
@@ -57,6 +128,87 @@ func (s *Server) handleDisplayGame(r *http.Request, game *mahjong.Game, _ uint64
 	}
 }
 
+func (s *Server) handleUndo(r *http.Request, game *mahjong.Game, id uint64) *Response {
+	steps := 1
+	if value := r.FormValue("steps"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return &Response{
+				StatusCode: http.StatusBadRequest,
+				Error:      fmt.Errorf("invalid steps value [%s]", value),
+			}
+		}
+		steps = n
+	}
+
+	err := game.Undo(steps)
+	if err != nil {
+		return &Response{
+			StatusCode: http.StatusBadRequest,
+			Error:      err,
+		}
+	}
+
+	return &Response{
+		StatusCode: http.StatusOK,
+		Data: &struct {
+			Message  string `json:"message"`
+			Location string `json:"location"`
+		}{
+			Message:  fmt.Sprintf("undid %d transitions", steps),
+			Location: fmt.Sprintf("%s/game/%d", s.GetDomain(true), id),
+		},
+	}
+}
+
+func (s *Server) handleLog(r *http.Request, game *mahjong.Game, _ uint64) *Response {
+	return &Response{
+		StatusCode: http.StatusOK,
+		Data:       game.Log(),
+	}
+}
+
+func (s *Server) handleSnapshot(r *http.Request, game *mahjong.Game, _ uint64) *Response {
+	return &Response{
+		StatusCode: http.StatusOK,
+		Data:       game.Snapshot(),
+	}
+}
+
+func (s *Server) handleRestore(r *http.Request) *Response {
+	var snapshot mahjong.Snapshot
+	err := json.NewDecoder(r.Body).Decode(&snapshot)
+	if err != nil {
+		return &Response{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Errorf("unable to parse snapshot: %s", err.Error()),
+		}
+	}
+
+	game, err := mahjong.Restore(snapshot)
+	if err != nil {
+		return &Response{
+			StatusCode: http.StatusBadRequest,
+			Error:      err,
+		}
+	}
+
+	id := s.Games.Add(game)
+
+	return &Response{
+		StatusCode: http.StatusCreated,
+		Data: &struct {
+			Message  string `json:"message"`
+			Id       uint64 `json:"id"`
+			Location string `json:"location"`
+		}{
+			Message:  "Game restored",
+			Id:       id,
+			Location: fmt.Sprintf("%s/game/%d", s.GetDomain(true), id),
+		},
+	}
+}
+
 func (s *Server) handleDisplayPlayer(r *http.Request, game *mahjong.Game, _ uint64) *Response {
 	player, err := intVar(mux.Vars(r), "player")
 	if err != nil {
@@ -65,10 +217,11 @@ func (s *Server) handleDisplayPlayer(r *http.Request, game *mahjong.Game, _ uint
 			Error:      err,
 		}
 	}
-	if player < 0 || player > 3 {
+	players := game.Table.GetPlayerCount()
+	if player < 0 || player >= players {
 		return &Response{
 			StatusCode: http.StatusBadRequest,
-			Error:      fmt.Errorf("player should be between 0 and 3 inclusive"),
+			Error:      fmt.Errorf("player should be between 0 and %d inclusive", players-1),
 		}
 	}
 	vectorized := false
@@ -92,14 +245,14 @@ func (s *Server) handleDisplayPlayer(r *http.Request, game *mahjong.Game, _ uint
 
 func (s *Server) handleActions(r *http.Request, game *mahjong.Game, id uint64) *Response {
 	actionMap := make(map[int]int)
-	for i, playerKey := range []int{0, 1, 2, 3} {
-		playerAction, err := strconv.ParseInt(r.PostForm.Get(fmt.Sprintf("%d", playerKey)), 10, 64)
+	for player := 0; player < game.Table.GetPlayerCount(); player++ {
+		playerAction, err := strconv.ParseInt(r.PostForm.Get(fmt.Sprintf("%d", player)), 10, 64)
 		if err == nil {
-			actionMap[i] = int(playerAction)
+			actionMap[player] = int(playerAction)
 		}
 	}
 
-	err := game.StateMachine.Transition(actionMap)
+	err := game.Transition(actionMap)
 	if err != nil {
 		if _, ok := err.(*state.IncorrectActionError); ok {
 			return &Response{
@@ -2,6 +2,7 @@ package mahjong
 
 import (
 	"github.com/roelofruis/mahjong-learn/state"
+	"sort"
 )
 
 // Tile received actions
@@ -17,6 +18,23 @@ type ExposedPungToKong struct{}
 
 func (d ExposedPungToKong) ActionOrder() int { return 200 }
 
+// Discard the tile and declare riichi.
+type DeclareRiichi struct{ Tile Tile }
+
+func (d DeclareRiichi) ActionOrder() int { return int(d.Tile) + 300 }
+
+// Declare the suit to get rid of before the hand can win, given by its first tile.
+type DeclareMissingSuit struct{ Suit Tile }
+
+func (d DeclareMissingSuit) ActionOrder() int { return int(d.Suit) + 400 }
+
+// Pass three tiles to another player during the Charleston.
+type PassTiles struct{ Tiles [3]Tile }
+
+func (d PassTiles) ActionOrder() int {
+	return 1000 + int(d.Tiles[0])*10000 + int(d.Tiles[1])*100 + int(d.Tiles[2])
+}
+
 // Tile discarded actions
 type DoNothing struct{}
 
@@ -34,77 +52,170 @@ type DeclareKong struct{}
 
 func (d DeclareKong) ActionOrder() int { return 101 }
 
-// Both received and discarded actions
+// Received, discarded and kong added actions
 type DeclareMahjong struct{}
 
 func (d DeclareMahjong) ActionOrder() int { return -1 }
 
 // Player actions
 
-func (p *Player) getDiscardAfterCombinationActions() []state.Action {
+// Actions at the start of the hand, when every player chooses the suit they will get rid of.
+func (p *Player) getMissingSuitActions() []state.Action {
+	return []state.Action{
+		DeclareMissingSuit{Suit: Bamboo1},
+		DeclareMissingSuit{Suit: Circles1},
+		DeclareMissingSuit{Suit: Characters1},
+	}
+}
+
+// Actions during the Charleston, when every player chooses three tiles to pass at the same time. Jokers cannot be passed.
+func (p *Player) getCharlestonActions() []state.Action {
+	tiles := make([]Tile, 0, p.concealed.Size())
+	for _, t := range p.concealed.list() {
+		if !t.IsJoker() {
+			tiles = append(tiles, t)
+		}
+	}
+	sort.Slice(tiles, func(i, j int) bool { return tiles[i] < tiles[j] })
+
 	availableActions := make([]state.Action, 0)
+	seen := make(map[PassTiles]bool)
+	for i := 0; i < len(tiles); i++ {
+		for j := i + 1; j < len(tiles); j++ {
+			for k := j + 1; k < len(tiles); k++ {
+				pass := PassTiles{Tiles: [3]Tile{tiles[i], tiles[j], tiles[k]}}
+				if !seen[pass] {
+					seen[pass] = true
+					availableActions = append(availableActions, pass)
+				}
+			}
+		}
+	}
 
+	return availableActions
+}
+
+func (p *Player) getDiscardAfterCombinationActions(rules Ruleset) []state.Action {
+	availableActions := make([]state.Action, 0)
+
+	mustDiscardMissing := p.holdsMissingSuit()
 	for t, c := range p.concealed.tiles {
-		availableActions = append(availableActions, Discard{Tile: t})
-		if c == 4 {
+		if !mustDiscardMissing || p.isMissingSuit(t) {
+			availableActions = append(availableActions, Discard{Tile: t})
+		}
+		if c == 4 && p.canDeclareConcealedKong(t, rules) {
 			availableActions = append(availableActions, DeclareConcealedKong{Tile: t})
 		}
 	}
 
-	// TODO: add declare mahjong
+	// No mahjong here: winning on a claimed tile is declared in response to the discard, not after forming the combination.
 
 	return availableActions
 }
 
-func (p *Player) getTileReceivedActions() []state.Action {
+// Actions after receiving a tile. canWin tells whether the received tile lets the player declare mahjong,
+// canRiichi whether the player may declare riichi on this turn.
+func (p *Player) getTileReceivedActions(rules Ruleset, canWin bool, canRiichi bool) []state.Action {
 	availableActions := make([]state.Action, 0)
 
 	receivedTile := *p.received
+	mustDiscardMissing := p.holdsMissingSuit()
 
-	availableActions = append(availableActions, Discard{Tile: receivedTile})
+	if !mustDiscardMissing || p.isMissingSuit(receivedTile) {
+		availableActions = append(availableActions, Discard{Tile: receivedTile})
+	}
+
+	if p.riichi != RiichiNone {
+		// after declaring riichi the hand is locked, the player can only discard the received tile.
+		if p.concealed.NumOf(receivedTile) == 3 && !rules.House.NoConcealedKongs && p.kongKeepsWaits(receivedTile, rules) {
+			availableActions = append(availableActions, DeclareConcealedKong{Tile: receivedTile})
+		}
+		if canWin {
+			availableActions = append(availableActions, DeclareMahjong{})
+		}
+		return availableActions
+	}
 
 	for t, c := range p.concealed.tiles {
-		if t != receivedTile {
+		if t != receivedTile && (!mustDiscardMissing || p.isMissingSuit(t)) {
 			availableActions = append(availableActions, Discard{Tile: t})
 		}
-		if c == 4 || (c == 3 && t == receivedTile) {
+		if (c == 4 || (c == 3 && t == receivedTile)) && p.canDeclareConcealedKong(t, rules) {
 			availableActions = append(availableActions, DeclareConcealedKong{Tile: t})
 		}
 	}
 
-	if p.exposed.Contains(Pung{Tile: receivedTile}) {
+	if p.exposed.Contains(Pung{Tile: receivedTile}) && !rules.House.NoKongClaims {
 		availableActions = append(availableActions, ExposedPungToKong{})
 	}
 
-	// TODO: add declare mahjong
+	if canRiichi {
+		hand := p.concealed.copy()
+		hand.add(receivedTile)
+		for _, t := range hand.withAtLeast(1) {
+			hand.remove(t)
+			if len(rules.WaitingTiles(hand, p.exposed)) > 0 {
+				availableActions = append(availableActions, DeclareRiichi{Tile: t})
+			}
+			hand.add(t)
+		}
+	}
+
+	if canWin {
+		availableActions = append(availableActions, DeclareMahjong{})
+	}
 
 	return availableActions
 }
 
-func (p *Player) getTileDiscardedActions(discarded Tile, isNextPlayer bool) []state.Action {
+// Actions in response to another player's discard. canWin tells whether the discard lets the player declare mahjong.
+func (p *Player) getTileDiscardedActions(discarded Tile, isNextPlayer bool, canWin bool, rules Ruleset) []state.Action {
 	availableActions := make([]state.Action, 0)
 
 	availableActions = append(availableActions, DoNothing{})
 
-	if p.concealed.NumOf(discarded) == 2 {
-		availableActions = append(availableActions, DeclarePung{})
-	}
+	// players in riichi can only claim a discard to win, and nobody claims jokers or tiles of their missing suit.
+	// Without claim interrupts only the next player can claim a set.
+	canClaimSet := isNextPlayer || !rules.House.NoClaimInterrupts
+	if p.riichi == RiichiNone && !p.isMissingSuit(discarded) && !discarded.IsJoker() && canClaimSet {
+		if p.concealed.NumOf(discarded) == 2 && rules.allowsClaim(ClaimPung) {
+			availableActions = append(availableActions, DeclarePung{})
+		}
 
-	if p.concealed.NumOf(discarded) == 3 {
-		availableActions = append(availableActions, DeclareKong{})
-	}
+		if p.concealed.NumOf(discarded) == 3 && rules.allowsClaim(ClaimKong) {
+			availableActions = append(availableActions, DeclareKong{})
+		}
 
-	if isNextPlayer {
-		for _, c := range possibleChows(p.concealed, discarded) {
-			availableActions = append(availableActions, DeclareChow{Tile: c})
+		if isNextPlayer && rules.allowsClaim(ClaimChow) {
+			for _, c := range possibleChows(p.concealed, discarded) {
+				availableActions = append(availableActions, DeclareChow{Tile: c})
+			}
 		}
 	}
 
-	// TODO: add declare mahjong
+	if canWin {
+		availableActions = append(availableActions, DeclareMahjong{})
+	}
 
 	return availableActions
 }
 
+// Actions in response to another player adding a tile to an exposed pung. canWin tells whether the player can rob the kong.
+func (p *Player) getKongAddedActions(canWin bool) []state.Action {
+	availableActions := []state.Action{DoNothing{}}
+
+	if canWin {
+		availableActions = append(availableActions, DeclareMahjong{})
+	}
+
+	return availableActions
+}
+
+// Whether the player may declare a concealed kong of the tile when holding all four. Tiles of the missing suit and jokers cannot form a kong.
+func (p *Player) canDeclareConcealedKong(tile Tile, rules Ruleset) bool {
+	return !rules.House.NoConcealedKongs && !p.isMissingSuit(tile) && !tile.IsJoker()
+}
+
 func possibleChows(hand *TileCollection, tile Tile) []Tile {
 	if !tile.IsSuit() {
 		return nil
@@ -0,0 +1,290 @@
+package mahjong
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Card lists the hands that win in American mahjong, as published each year. Any other hand does not win.
+type Card struct {
+	Name  string     `json:"name"`
+	Hands []CardHand `json:"hands"`
+}
+
+// CardHand is a single pattern on the card.
+//
+// The pattern consists of groups of identical tiles separated by spaces, such as "FF 2222a 44b 6666a".
+// A group is a symbol repeated for every tile, followed by a suit letter for numbers and dragons.
+// Symbols are the numbers 1 to 9, D for the dragon of the suit, 0 for the white dragon, N, E, W and S for the winds and F for any flower or season.
+// Suit letters a, b and c stand for different suits, which can be any of the three.
+type CardHand struct {
+	// Section of the card the hand is listed in.
+	Section string `json:"section"`
+	Pattern string `json:"pattern"`
+	// Amount the hand is worth.
+	Value int `json:"value"`
+	// Whether the hand has to be completed without claiming discards, except for the winning tile.
+	Concealed bool `json:"concealed"`
+	// Whether all numbers in the pattern may be raised by the same amount, for hands of consecutive or like numbers.
+	Shift bool `json:"shift"`
+
+	groups []cardGroup
+	// Suit orders to try, one for every way the suit letters can stand for different suits.
+	suitOrders [][]Tile
+	// Highest number in the pattern, zero if it has none.
+	maxNumber int
+}
+
+// cardGroup is a group of identical tiles in a pattern.
+type cardGroup struct {
+	symbol byte
+	// Suit letter, zero for tiles that have no suit.
+	suit byte
+	size int
+}
+
+// LoadCard reads a card from a JSON file.
+func LoadCard(path string) (*Card, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	card := &Card{}
+	err = json.Unmarshal(data, card)
+	if err != nil {
+		return nil, fmt.Errorf("invalid card [%s]: %s", path, err.Error())
+	}
+
+	for i := range card.Hands {
+		err = card.Hands[i].parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid card [%s]: %s", path, err.Error())
+		}
+	}
+
+	return card, nil
+}
+
+func (h *CardHand) parse() error {
+	h.groups = nil
+	h.maxNumber = 0
+	size := 0
+	letters := byte(0)
+	for _, group := range strings.Fields(h.Pattern) {
+		symbol := group[0]
+		suit := byte(0)
+		n := len(group)
+		if last := group[n-1]; last >= 'a' && last <= 'c' {
+			suit = last
+			n--
+		}
+		if n == 0 || strings.Count(group[:n], string(symbol)) != n {
+			return fmt.Errorf("group [%s] of pattern [%s] is not a repeated symbol", group, h.Pattern)
+		}
+
+		needsSuit := (symbol >= '1' && symbol <= '9') || symbol == 'D'
+		if !strings.ContainsRune("123456789D0NEWSF", rune(symbol)) || needsSuit != (suit != 0) {
+			return fmt.Errorf("group [%s] of pattern [%s] has an unknown symbol or a missing suit", group, h.Pattern)
+		}
+
+		h.groups = append(h.groups, cardGroup{symbol: symbol, suit: suit, size: n})
+		size += n
+		if suit > letters {
+			letters = suit
+		}
+		if number := int(symbol - '0'); symbol >= '1' && symbol <= '9' && number > h.maxNumber {
+			h.maxNumber = number
+		}
+	}
+
+	if size != 14 {
+		return fmt.Errorf("pattern [%s] has [%d] tiles instead of 14", h.Pattern, size)
+	}
+
+	// matching fills the smallest groups first
+	sort.SliceStable(h.groups, func(i, j int) bool { return h.groups[i].size < h.groups[j].size })
+
+	// orders that only differ in suits the pattern does not use are the same
+	h.suitOrders = nil
+	used := 0
+	if letters != 0 {
+		used = int(letters-'a') + 1
+	}
+	seen := make(map[[3]Tile]bool)
+	for _, order := range cardSuitOrders {
+		var key [3]Tile
+		copy(key[:used], order[:used])
+		if !seen[key] {
+			seen[key] = true
+			h.suitOrders = append(h.suitOrders, order)
+		}
+	}
+	return nil
+}
+
+// The hands on the card that the tiles match. The concealed tiles include the winning tile.
+func (c *Card) matches(concealed *TileCollection, exposed *CombinationCollection) []CardHand {
+	matches := make([]CardHand, 0)
+	for _, hand := range c.Hands {
+		if hand.matches(concealed, exposed) {
+			matches = append(matches, hand)
+		}
+	}
+	return matches
+}
+
+// Suits the suit letters of a pattern can stand for, by their first tile.
+var cardSuitOrders = [][]Tile{
+	{Bamboo1, Circles1, Characters1},
+	{Bamboo1, Characters1, Circles1},
+	{Circles1, Bamboo1, Characters1},
+	{Circles1, Characters1, Bamboo1},
+	{Characters1, Bamboo1, Circles1},
+	{Characters1, Circles1, Bamboo1},
+}
+
+// The dragon that belongs to each suit.
+var suitDragons = map[Tile]Tile{
+	Bamboo1:     GreenDragon,
+	Characters1: RedDragon,
+	Circles1:    WhiteDragon,
+}
+
+// Number of tiles per kind that a hand is matched on, with all flowers and seasons counted as one kind.
+type cardCounts [Joker + 1]int
+
+func (h CardHand) matches(concealed *TileCollection, exposed *CombinationCollection) bool {
+	if h.Concealed {
+		for _, c := range exposed.combinations {
+			if _, isBonus := c.(BonusTile); !isBonus {
+				return false
+			}
+		}
+	}
+
+	var counts cardCounts
+	for tile, count := range concealed.tiles {
+		if tile.IsBonusTile() {
+			tile = FlowerPlumb
+		}
+		counts[tile] += int(count)
+	}
+
+	maxShift := 0
+	if h.Shift && h.maxNumber > 0 {
+		maxShift = 9 - h.maxNumber
+	}
+	tiles := make([]Tile, len(h.groups))
+	remaining := make([]int, len(h.groups))
+	for shift := 0; shift <= maxShift; shift++ {
+		for _, suits := range h.suitOrders {
+			if h.expand(tiles, suits, shift) && fitsGroups(tiles, h.groups, remaining, counts, exposed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Set the tile of every group, with suit letters a, b and c standing for the given suits and numbers raised by shift.
+// Not valid if a number is raised beyond nine.
+func (h CardHand) expand(tiles []Tile, suits []Tile, shift int) bool {
+	for i, g := range h.groups {
+		var suit Tile
+		if g.suit != 0 {
+			suit = suits[g.suit-'a']
+		}
+		switch g.symbol {
+		case 'D':
+			tiles[i] = suitDragons[suit]
+		case '0':
+			tiles[i] = WhiteDragon
+		case 'N':
+			tiles[i] = NorthWind
+		case 'E':
+			tiles[i] = EastWind
+		case 'W':
+			tiles[i] = WestWind
+		case 'S':
+			tiles[i] = SouthWind
+		case 'F':
+			tiles[i] = FlowerPlumb
+		default:
+			number := int(g.symbol-'0') + shift
+			if number > 9 {
+				return false
+			}
+			tiles[i] = suit + Tile(number-1)
+		}
+	}
+	return true
+}
+
+// Whether the concealed tiles, given as counts, and the exposed sets fill the groups exactly. Every exposed set has to be one of the groups,
+// jokers can fill the rest of groups of three or more tiles. The groups are ordered from small to large.
+func fitsGroups(tiles []Tile, groups []cardGroup, remaining []int, counts cardCounts, exposed *CombinationCollection) bool {
+	for i, g := range groups {
+		remaining[i] = g.size
+	}
+
+	for _, c := range exposed.combinations {
+		var tile Tile
+		var size int
+		switch comb := c.(type) {
+		case Pung:
+			tile, size = comb.Tile, 3
+		case Kong:
+			tile, size = comb.Tile, 4
+		case BonusTile:
+			continue
+		default:
+			return false
+		}
+
+		found := false
+		for i := range groups {
+			if tiles[i] == tile && remaining[i] == size {
+				remaining[i] = 0
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	jokers := counts[Joker]
+	counts[Joker] = 0
+
+	// pairs and single tiles cannot use jokers, so they take the natural tiles first
+	for i := range groups {
+		if remaining[i] == 0 {
+			continue
+		}
+		natural := counts[tiles[i]]
+		if natural > remaining[i] {
+			natural = remaining[i]
+		}
+		counts[tiles[i]] -= natural
+		missing := remaining[i] - natural
+		if missing > 0 && groups[i].size < 3 {
+			return false
+		}
+		jokers -= missing
+		if jokers < 0 {
+			return false
+		}
+	}
+
+	for _, count := range counts {
+		if count > 0 {
+			return false
+		}
+	}
+	return jokers == 0
+}
@@ -0,0 +1,101 @@
+package mahjong
+
+import (
+	"github.com/roelofruis/mahjong-learn/state"
+)
+
+// Clone makes a deep copy of the game, which can be played on without affecting the original.
+// The clone shuffles the same walls as the original, so given the same actions both play out the same.
+func (g *Game) Clone() *Game {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.clone(g.Table.clone())
+}
+
+// CloneWithSeed makes a deep copy of the game that continues with a new seed. The tiles left in the live wall
+// are shuffled again and the walls of the following hands come from the new seed, so clones with different seeds
+// branch into different futures. The new seed is recorded in the log of the clone, so the clone can still be replayed.
+func (g *Game) CloneWithSeed(seed int64) *Game {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	clone := g.clone(g.Table.clone())
+	clone.reseedAt([]Reseed{{Step: len(clone.log.Actions), Seed: seed}}, len(clone.log.Actions))
+
+	return clone
+}
+
+// Copy the game onto the cloned table. The current state is generated again by the generator that produced it,
+// so its transitions act on the clone. The clone gets its own transitioner, configured like the one of the game.
+func (g *Game) clone(table *Table) *Game {
+	log := g.log.copy()
+
+	return &Game{
+		Table:        table,
+		StateMachine: state.NewStateMachine(table.waitingIn(table), g.StateMachine.Transitioner().Clone()),
+		log:          &log,
+	}
+}
+
+func (t *Table) clone() *Table {
+	c := *t
+
+	c.wall = t.wall.clone()
+	c.activeDiscard = copyTile(t.activeDiscard)
+	c.addedKongTile = copyTile(t.addedKongTile)
+
+	c.players = make(map[int]*Player, len(t.players))
+	for s, p := range t.players {
+		c.players[s] = p.clone()
+	}
+
+	c.wins = nil
+	for _, w := range t.wins {
+		w.Hand.Concealed = w.Hand.Concealed.copy()
+		w.Hand.Exposed = w.Hand.Exposed.copy()
+		c.wins = append(c.wins, w)
+	}
+
+	c.setRandomSource(&randomSource{state: t.random.state})
+
+	return &c
+}
+
+// Continue with a new seed, shuffling the tiles that are left in the live wall.
+func (t *Table) reseed(seed int64) {
+	t.seed = seed
+	t.setRandomSource(newRandomSource(seed))
+	t.rng.Shuffle(len(t.wall.live), func(i, j int) {
+		t.wall.live[i], t.wall.live[j] = t.wall.live[j], t.wall.live[i]
+	})
+}
+
+func (w *Wall) clone() *Wall {
+	return &Wall{
+		live:       append(make([]wallTile, 0, len(w.live)), w.live...),
+		dead:       append(make([]wallTile, 0, len(w.dead)), w.dead...),
+		indicators: append(make([]wallTile, 0, len(w.indicators)), w.indicators...),
+	}
+}
+
+func (p *Player) clone() *Player {
+	c := *p
+
+	c.received = copyTile(p.received)
+	c.concealed = p.concealed.copy()
+	c.exposed = p.exposed.copy()
+	c.discarded = p.discarded.copy()
+	c.discards = append(make([]DiscardedTile, 0, len(p.discards)), p.discards...)
+
+	c.redFives = make(map[Tile]int, len(p.redFives))
+	for tile, n := range p.redFives {
+		c.redFives[tile] = n
+	}
+
+	return &c
+}
+
+func (c *CombinationCollection) copy() *CombinationCollection {
+	return &CombinationCollection{combinations: append(make([]Combination, 0, len(c.combinations)), c.combinations...)}
+}
@@ -0,0 +1,500 @@
+package mahjong
+
+import "sort"
+
+// HandForm is the overall shape of a complete hand.
+type HandForm int
+
+const (
+	// Four sets and a single pair.
+	FormRegular HandForm = iota
+	// Seven pairs and no sets.
+	FormSevenPairs
+	// One of each terminal and honor, one of them doubled.
+	FormThirteenOrphans
+	// A knitted straight, which is 147, 258 and 369 each in a different suit, together with one set and a pair.
+	FormKnittedStraight
+	// Fourteen different tiles, all either honors or part of a single knitted straight.
+	FormHonorsAndKnitted
+)
+
+// Decomposition is one way of reading a complete hand as sets and a single pair, or as one of the special forms.
+type Decomposition struct {
+	Form HandForm
+	// The pair of a regular hand, or the doubled tile of thirteen orphans.
+	Pair Tile
+	// The pairs of a seven pairs hand, from low to high.
+	Pairs []Tile
+	// The suit tiles of a knitted straight or honors and knitted tiles hand, from low to high.
+	Knitted []Tile
+	// Sets formed out of the concealed tiles, starting from the lowest tile.
+	Concealed []Combination
+	// Sets that were declared before, excluding bonus tiles. Kongs only ever appear here, as they have to be declared.
+	Exposed []Combination
+}
+
+// All sets in the decomposition, both concealed and exposed.
+func (d Decomposition) Sets() []Combination {
+	sets := make([]Combination, 0, len(d.Concealed)+len(d.Exposed))
+	sets = append(sets, d.Concealed...)
+	sets = append(sets, d.Exposed...)
+	return sets
+}
+
+// Decompose returns every way the concealed tiles can be split into chows, pungs and a single pair.
+// The exposed combinations are added to each decomposition as they are. If the tiles cannot be split, no decompositions are returned.
+//
+// Ambiguous hands yield one decomposition per interpretation, so 111222333 in a single suit is returned both as three pungs and as three chows.
+// Fully concealed hands are also read as seven pairs, thirteen orphans and honors and knitted tiles, and hands with a knitted straight
+// as that straight, a set and a pair. Whether these forms count is up to the ruleset.
+func Decompose(concealed *TileCollection, exposed *CombinationCollection) []Decomposition {
+	decompositions := make([]Decomposition, 0)
+
+	counts := countsOf(concealed)
+	if counts.size()%3 != 2 {
+		return decompositions
+	}
+
+	// Jokers only stand in for other tiles when matched against a card, they do not form sets of their own
+	if counts[Joker] > 0 {
+		return decompositions
+	}
+
+	exposedSets := make([]Combination, 0)
+	if exposed != nil {
+		for _, c := range exposed.combinations {
+			if _, isBonus := c.(BonusTile); !isBonus {
+				exposedSets = append(exposedSets, c)
+			}
+		}
+	}
+
+	for pair := Tile(0); pair < maxTile; pair++ {
+		if counts[pair] < 2 {
+			continue
+		}
+		counts[pair] -= 2
+		for _, sets := range counts.splitIntoSets(0) {
+			decompositions = append(decompositions, Decomposition{
+				Form:      FormRegular,
+				Pair:      pair,
+				Concealed: sets,
+				Exposed:   exposedSets,
+			})
+		}
+		counts[pair] += 2
+	}
+
+	if counts.isSevenPairs() {
+		pairs := make([]Tile, 0, 7)
+		for tile := Tile(0); tile < maxTile; tile++ {
+			for i := uint8(0); i < counts[tile]/2; i++ {
+				pairs = append(pairs, tile)
+			}
+		}
+		decompositions = append(decompositions, Decomposition{
+			Form:      FormSevenPairs,
+			Pairs:     pairs,
+			Concealed: []Combination{},
+			Exposed:   exposedSets,
+		})
+	}
+
+	if counts.isThirteenOrphans() {
+		for _, tile := range orphans {
+			if counts[tile] == 2 {
+				decompositions = append(decompositions, Decomposition{
+					Form:      FormThirteenOrphans,
+					Pair:      tile,
+					Concealed: []Combination{},
+					Exposed:   exposedSets,
+				})
+			}
+		}
+	}
+
+	for _, knitted := range knittedStraights {
+		if !counts.contains(knitted) {
+			continue
+		}
+		counts.take(knitted, -1)
+		for pair := Tile(0); pair < maxTile; pair++ {
+			if counts[pair] < 2 {
+				continue
+			}
+			counts[pair] -= 2
+			for _, sets := range counts.splitIntoSets(0) {
+				decompositions = append(decompositions, Decomposition{
+					Form:      FormKnittedStraight,
+					Pair:      pair,
+					Knitted:   sortedTiles(knitted),
+					Concealed: sets,
+					Exposed:   exposedSets,
+				})
+			}
+			counts[pair] += 2
+		}
+		counts.take(knitted, 1)
+	}
+
+	if counts.isHonorsAndKnitted() {
+		knitted := make([]Tile, 0, 9)
+		for tile := Tile(0); tile < maxTile; tile++ {
+			if counts[tile] > 0 && tile.IsSuit() {
+				knitted = append(knitted, tile)
+			}
+		}
+		decompositions = append(decompositions, Decomposition{
+			Form:      FormHonorsAndKnitted,
+			Knitted:   knitted,
+			Concealed: []Combination{},
+			Exposed:   exposedSets,
+		})
+	}
+
+	return decompositions
+}
+
+// Whether the hand is a concealed nine gates: 1112345678999 in a single suit, plus any tile of that suit.
+func IsNineGates(concealed *TileCollection, exposed *CombinationCollection) bool {
+	if exposed != nil {
+		for _, c := range exposed.combinations {
+			if _, isBonus := c.(BonusTile); !isBonus {
+				return false
+			}
+		}
+	}
+
+	counts := countsOf(concealed)
+	if counts.size() != 14 {
+		return false
+	}
+
+	for suit := Bamboo1; suit <= Characters1; suit += 10 {
+		if counts[suit] < 3 || counts[suit+8] < 3 {
+			continue
+		}
+		inSuit := 0
+		for number := Tile(0); number < 9; number++ {
+			if counts[suit+number] == 0 {
+				break
+			}
+			inSuit += int(counts[suit+number])
+		}
+		if inSuit == 14 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The thirteen terminals and honors that make up thirteen orphans.
+var orphans = []Tile{
+	Bamboo1, Bamboo9, Circles1, Circles9, Characters1, Characters9,
+	RedDragon, GreenDragon, WhiteDragon,
+	EastWind, SouthWind, WestWind, NorthWind,
+}
+
+// The six knitted straights: 147, 258 and 369 each in a different suit.
+var knittedStraights = func() [][]Tile {
+	suits := []Tile{Bamboo1, Circles1, Characters1}
+	orders := [][]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+
+	straights := make([][]Tile, 0, len(orders))
+	for _, order := range orders {
+		straight := make([]Tile, 0, 9)
+		for offset, suit := range order {
+			for number := Tile(offset); number < 9; number += 3 {
+				straight = append(straight, suits[suit]+number)
+			}
+		}
+		straights = append(straights, straight)
+	}
+	return straights
+}()
+
+func sortedTiles(tiles []Tile) []Tile {
+	sorted := make([]Tile, len(tiles))
+	copy(sorted, tiles)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// Upper bound of the tile values, used to size tileCounts. The joker is the highest tile.
+const maxTile = Joker + 1
+
+// Number of tiles per tile value. Hands are evaluated on these counts rather than on a TileCollection,
+// because evaluating is done for every player on every discard and needs to be fast.
+type tileCounts [maxTile]uint8
+
+func countsOf(c *TileCollection) tileCounts {
+	var counts tileCounts
+	for tile, count := range c.tiles {
+		counts[tile] = count
+	}
+	return counts
+}
+
+func (c *tileCounts) size() int {
+	size := 0
+	for _, count := range c {
+		size += int(count)
+	}
+	return size
+}
+
+// Whether the tiles can be split into sets and a single pair.
+func (c *tileCounts) isComplete() bool {
+	if c.size()%3 != 2 {
+		return false
+	}
+
+	for pair := Tile(0); pair < maxTile; pair++ {
+		if c[pair] < 2 {
+			continue
+		}
+		c[pair] -= 2
+		complete := c.formsSets(0)
+		c[pair] += 2
+		if complete {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Whether the tiles form seven pairs. Four of a kind counts as two pairs, rulesets can forbid it with SpecialHands.DistinctPairs.
+func (c *tileCounts) isSevenPairs() bool {
+	if c.size() != 14 {
+		return false
+	}
+	for _, count := range c {
+		if count%2 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether the tiles form thirteen orphans.
+func (c *tileCounts) isThirteenOrphans() bool {
+	if c.size() != 14 {
+		return false
+	}
+	inHand := 0
+	for _, tile := range orphans {
+		if c[tile] == 0 {
+			return false
+		}
+		inHand += int(c[tile])
+	}
+	return inHand == 14
+}
+
+// Whether the tiles form a knitted straight together with a set and a pair.
+func (c *tileCounts) isKnittedStraight() bool {
+	for _, knitted := range knittedStraights {
+		if !c.contains(knitted) {
+			continue
+		}
+		c.take(knitted, -1)
+		complete := c.isComplete()
+		c.take(knitted, 1)
+		if complete {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the tiles are fourteen different honors and tiles of a single knitted straight.
+func (c *tileCounts) isHonorsAndKnitted() bool {
+	if c.size() != 14 {
+		return false
+	}
+	for _, count := range c {
+		if count > 1 {
+			return false
+		}
+	}
+	for _, knitted := range knittedStraights {
+		inHand := 0
+		for _, tile := range knitted {
+			inHand += int(c[tile])
+		}
+		for _, tile := range []Tile{RedDragon, GreenDragon, WhiteDragon, EastWind, SouthWind, WestWind, NorthWind} {
+			inHand += int(c[tile])
+		}
+		if inHand == 14 {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether there is at least one of each of the tiles.
+func (c *tileCounts) contains(tiles []Tile) bool {
+	for _, tile := range tiles {
+		if c[tile] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Remove (delta -1) or restore (delta 1) one of each of the tiles.
+func (c *tileCounts) take(tiles []Tile, delta int) {
+	for _, tile := range tiles {
+		c[tile] = uint8(int(c[tile]) + delta)
+	}
+}
+
+// Whether all tiles from tile `from` up can be split into chows and pungs. Lower tiles should already be used.
+func (c *tileCounts) formsSets(from Tile) bool {
+	lowest := c.lowestFrom(from)
+	if lowest == maxTile {
+		return true
+	}
+
+	if c[lowest] >= 3 {
+		c[lowest] -= 3
+		complete := c.formsSets(lowest)
+		c[lowest] += 3
+		if complete {
+			return true
+		}
+	}
+
+	if !c.hasChowFrom(lowest) {
+		return false
+	}
+
+	c.takeChow(lowest, -1)
+	complete := c.formsSets(lowest)
+	c.takeChow(lowest, 1)
+
+	return complete
+}
+
+// All ways to split the tiles from tile `from` up into chows and pungs. Lower tiles should already be used.
+// The lowest tile always starts a set, which guarantees every split is returned exactly once.
+func (c *tileCounts) splitIntoSets(from Tile) [][]Combination {
+	lowest := c.lowestFrom(from)
+	if lowest == maxTile {
+		return [][]Combination{{}}
+	}
+
+	splits := make([][]Combination, 0)
+
+	if c[lowest] >= 3 {
+		c[lowest] -= 3
+		for _, rest := range c.splitIntoSets(lowest) {
+			splits = append(splits, append([]Combination{Pung{Tile: lowest}}, rest...))
+		}
+		c[lowest] += 3
+	}
+
+	if c.hasChowFrom(lowest) {
+		c.takeChow(lowest, -1)
+		for _, rest := range c.splitIntoSets(lowest) {
+			splits = append(splits, append([]Combination{Chow{FirstTile: lowest}}, rest...))
+		}
+		c.takeChow(lowest, 1)
+	}
+
+	return splits
+}
+
+func (c *tileCounts) lowestFrom(from Tile) Tile {
+	for tile := from; tile < maxTile; tile++ {
+		if c[tile] > 0 {
+			return tile
+		}
+	}
+	return maxTile
+}
+
+func (c *tileCounts) hasChowFrom(first Tile) bool {
+	return first.IsSuit() && first%10 <= 7 && c[first+1] > 0 && c[first+2] > 0
+}
+
+// Remove (delta -1) or restore (delta 1) the chow starting at the given tile.
+func (c *tileCounts) takeChow(first Tile, delta int) {
+	c[first] = uint8(int(c[first]) + delta)
+	c[first+1] = uint8(int(c[first+1]) + delta)
+	c[first+2] = uint8(int(c[first+2]) + delta)
+}
+
+// Wait describes which part of a decomposition was completed by the winning tile.
+type Wait int
+
+const (
+	// The winning tile completed the pair.
+	WaitPair Wait = iota
+	// The winning tile completed a pung, waiting on one of two pairs.
+	WaitPung
+	// The winning tile is the 3 of a 1-2-3 chow or the 7 of a 7-8-9 chow.
+	WaitEdge
+	// The winning tile is the middle tile of a chow.
+	WaitClosed
+	// The winning tile is either end of a chow that could have been completed on both sides.
+	WaitOpen
+	// The winning tile is part of a knitted straight or honors and knitted tiles hand.
+	WaitKnitted
+)
+
+// All ways in which the winning tile can have completed this decomposition.
+// A tile can be part of several concealed sets, in which case the scoring systems pick the most valuable reading.
+func (d Decomposition) Waits(winningTile Tile) []Wait {
+	waits := make([]Wait, 0)
+
+	if d.Form == FormSevenPairs || d.Form == FormThirteenOrphans {
+		// special forms always wait on a single tile
+		return append(waits, WaitPair)
+	}
+
+	if d.Form == FormHonorsAndKnitted {
+		return append(waits, WaitKnitted)
+	}
+
+	for _, tile := range d.Knitted {
+		if tile == winningTile {
+			waits = append(waits, WaitKnitted)
+		}
+	}
+
+	if d.Pair == winningTile {
+		waits = append(waits, WaitPair)
+	}
+
+	for _, set := range d.Concealed {
+		switch c := set.(type) {
+		case Pung:
+			if c.Tile == winningTile {
+				waits = append(waits, WaitPung)
+			}
+		case Chow:
+			number := int(c.FirstTile) % 10
+			switch winningTile {
+			case c.FirstTile + 1:
+				waits = append(waits, WaitClosed)
+			case c.FirstTile:
+				if number == 7 {
+					waits = append(waits, WaitEdge)
+				} else {
+					waits = append(waits, WaitOpen)
+				}
+			case c.FirstTile + 2:
+				if number == 1 {
+					waits = append(waits, WaitEdge)
+				} else {
+					waits = append(waits, WaitOpen)
+				}
+			}
+		}
+	}
+
+	return waits
+}
@@ -0,0 +1,252 @@
+package mahjong
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsWinningHand(t *testing.T) {
+	cases := []struct {
+		name      string
+		concealed []Tile
+		tile      Tile
+		winning   bool
+	}{
+		{
+			name:      "four sets and a pair",
+			concealed: []Tile{Bamboo1, Bamboo2, Bamboo3, Circles5, Circles5, Circles5, Characters7, Characters8, Characters9, RedDragon, RedDragon, EastWind, EastWind},
+			tile:      EastWind,
+			winning:   true,
+		},
+		{
+			name:      "waiting on the pair",
+			concealed: []Tile{Bamboo1, Bamboo2, Bamboo3, Circles5, Circles5, Circles5, Characters7, Characters8, Characters9, RedDragon, RedDragon, RedDragon, NorthWind},
+			tile:      NorthWind,
+			winning:   true,
+		},
+		{
+			name:      "ambiguous run of pungs and chows",
+			concealed: []Tile{Bamboo1, Bamboo1, Bamboo1, Bamboo2, Bamboo2, Bamboo2, Bamboo3, Bamboo3, Bamboo3, Bamboo4, Circles9, Circles9, Circles9},
+			tile:      Bamboo4,
+			winning:   true,
+		},
+		{
+			name:      "chow does not wrap around suits",
+			concealed: []Tile{Bamboo8, Bamboo9, Circles1, Circles5, Circles5, Circles5, Characters7, Characters8, Characters9, RedDragon, RedDragon, RedDragon, NorthWind},
+			tile:      NorthWind,
+			winning:   false,
+		},
+		{
+			name:      "honours do not form chows",
+			concealed: []Tile{EastWind, SouthWind, WestWind, Circles5, Circles5, Circles5, Characters7, Characters8, Characters9, RedDragon, RedDragon, RedDragon, NorthWind},
+			tile:      NorthWind,
+			winning:   false,
+		},
+		{
+			name:      "hand completed by exposed combinations",
+			concealed: []Tile{Circles2, Circles3, Circles4, WhiteDragon},
+			tile:      WhiteDragon,
+			winning:   true,
+		},
+		{
+			name:      "one tile short",
+			concealed: []Tile{Circles2, Circles3, Circles4, WhiteDragon, WhiteDragon},
+			tile:      GreenDragon,
+			winning:   false,
+		},
+	}
+
+	rules := ClassicalRuleset()
+	for _, c := range cases {
+		if rules.isWinningHand(tilesOf(c.concealed...), newCombinationCollection(), c.tile) != c.winning {
+			t.Errorf("%s: expected winning to be [%t]", c.name, c.winning)
+		}
+	}
+}
+
+func tilesOf(tiles ...Tile) *TileCollection {
+	collection := newEmptyTileCollection()
+	for _, tile := range tiles {
+		collection.add(tile)
+	}
+	return collection
+}
+
+func TestDecompose(t *testing.T) {
+	concealed := tilesOf(Bamboo1, Bamboo1, Bamboo1, Bamboo2, Bamboo2, Bamboo2, Bamboo3, Bamboo3, Bamboo3, RedDragon, RedDragon)
+	exposed := newCombinationCollection()
+	exposed.add(Kong{Tile: EastWind})
+	exposed.add(BonusTile{Tile: FlowerPlumb})
+
+	decompositions := Decompose(concealed, exposed)
+	if len(decompositions) != 2 {
+		t.Fatalf("expected two decompositions, got [%d]: %+v", len(decompositions), decompositions)
+	}
+
+	expected := [][]Combination{
+		{Pung{Tile: Bamboo1}, Pung{Tile: Bamboo2}, Pung{Tile: Bamboo3}},
+		{Chow{FirstTile: Bamboo1}, Chow{FirstTile: Bamboo1}, Chow{FirstTile: Bamboo1}},
+	}
+	for _, sets := range expected {
+		found := false
+		for _, d := range decompositions {
+			if d.Pair == RedDragon && equalCombinations(d.Concealed, sets) && equalCombinations(d.Exposed, []Combination{Kong{Tile: EastWind}}) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected decomposition with sets %+v", sets)
+		}
+	}
+
+	if len(Decompose(tilesOf(Bamboo1, Bamboo2, Bamboo4, RedDragon, RedDragon), nil)) != 0 {
+		t.Errorf("expected no decompositions for an incomplete hand")
+	}
+
+	if len(Decompose(tilesOf(Bamboo1, Bamboo2, Bamboo3, RedDragon, RedDragon, Joker, Joker, Joker), nil)) != 0 {
+		t.Errorf("expected no decompositions for a hand with jokers")
+	}
+}
+
+func equalCombinations(a []Combination, b []Combination) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSpecialHands(t *testing.T) {
+	sevenPairs := []Tile{Bamboo1, Bamboo1, Bamboo5, Bamboo5, Circles2, Circles2, Circles8, Circles8, Characters3, Characters3, RedDragon, RedDragon, EastWind}
+	fourOfAKindPairs := []Tile{Bamboo1, Bamboo1, Bamboo1, Bamboo1, Circles2, Circles2, Circles8, Circles8, Characters3, Characters3, RedDragon, RedDragon, EastWind}
+	thirteenOrphans := []Tile{Bamboo1, Bamboo9, Circles1, Circles9, Characters1, Characters9, RedDragon, GreenDragon, WhiteDragon, EastWind, SouthWind, WestWind, NorthWind}
+	knittedStraight := []Tile{Bamboo1, Bamboo4, Bamboo7, Circles2, Circles5, Circles8, Characters3, Characters6, Characters9, RedDragon, RedDragon, RedDragon, EastWind}
+	honorsAndKnitted := []Tile{Bamboo1, Bamboo4, Bamboo7, Circles2, Circles5, Circles8, Characters3, Characters6, EastWind, SouthWind, WestWind, NorthWind, RedDragon}
+
+	cases := []struct {
+		name      string
+		special   SpecialHands
+		concealed []Tile
+		tile      Tile
+		winning   bool
+	}{
+		{name: "seven pairs disabled", special: SpecialHands{}, concealed: sevenPairs, tile: EastWind, winning: false},
+		{name: "seven pairs enabled", special: SpecialHands{SevenPairs: true}, concealed: sevenPairs, tile: EastWind, winning: true},
+		{name: "four of a kind as two pairs", special: SpecialHands{SevenPairs: true}, concealed: fourOfAKindPairs, tile: EastWind, winning: true},
+		{name: "four of a kind with distinct pairs", special: SpecialHands{SevenPairs: true, DistinctPairs: true}, concealed: fourOfAKindPairs, tile: EastWind, winning: false},
+		{name: "thirteen orphans disabled", special: SpecialHands{}, concealed: thirteenOrphans, tile: NorthWind, winning: false},
+		{name: "thirteen orphans enabled", special: SpecialHands{ThirteenOrphans: true}, concealed: thirteenOrphans, tile: NorthWind, winning: true},
+		{name: "thirteen orphans with a simple", special: SpecialHands{ThirteenOrphans: true}, concealed: thirteenOrphans, tile: Bamboo5, winning: false},
+		{name: "knitted straight disabled", special: SpecialHands{}, concealed: knittedStraight, tile: EastWind, winning: false},
+		{name: "knitted straight enabled", special: SpecialHands{Knitted: true}, concealed: knittedStraight, tile: EastWind, winning: true},
+		{name: "honors and knitted tiles enabled", special: SpecialHands{Knitted: true}, concealed: honorsAndKnitted, tile: GreenDragon, winning: true},
+		{name: "honors and knitted tiles with a double", special: SpecialHands{Knitted: true}, concealed: honorsAndKnitted, tile: RedDragon, winning: false},
+		{name: "honors and knitted tiles from two straights", special: SpecialHands{Knitted: true}, concealed: honorsAndKnitted, tile: Characters2, winning: false},
+	}
+
+	for _, c := range cases {
+		rules := Ruleset{SpecialHands: c.special}
+		if rules.isWinningHand(tilesOf(c.concealed...), newCombinationCollection(), c.tile) != c.winning {
+			t.Errorf("%s: expected winning to be [%t]", c.name, c.winning)
+		}
+	}
+
+	rules := Ruleset{Tiles: TileSet{Honors: true}, SpecialHands: SpecialHands{ThirteenOrphans: true}}
+	if waits := rules.WaitingTiles(tilesOf(thirteenOrphans...), newCombinationCollection()); len(waits) != 13 {
+		t.Errorf("expected a thirteen sided wait, got %v", waits)
+	}
+}
+
+func TestWaitingTilesInTileSet(t *testing.T) {
+	sets := []Tile{Circles1, Circles2, Circles3, Circles4, Circles5, Circles6, Circles7, Circles8, Circles9}
+
+	cases := []struct {
+		name      string
+		tiles     TileSet
+		concealed []Tile
+		waits     []Tile
+	}{
+		{name: "open wait", tiles: TileSet{Honors: true}, concealed: append([]Tile{Bamboo2, Bamboo3, Characters1, Characters1}, sets...), waits: []Tile{Bamboo1, Bamboo4}},
+		{name: "excluded tile", tiles: TileSet{Honors: true, Excluded: []Tile{Bamboo4}}, concealed: append([]Tile{Bamboo2, Bamboo3, Characters1, Characters1}, sets...), waits: []Tile{Bamboo1}},
+		{name: "honors left out", tiles: TileSet{}, concealed: append([]Tile{EastWind, Bamboo1, Bamboo1, Bamboo1}, sets...), waits: []Tile{}},
+		{name: "bonus honor", tiles: TileSet{Honors: true, BonusHonors: []Tile{NorthWind}}, concealed: append([]Tile{NorthWind, Bamboo1, Bamboo1, Bamboo1}, sets...), waits: []Tile{}},
+	}
+
+	for _, c := range cases {
+		rules := Ruleset{Tiles: c.tiles}
+		if waits := rules.WaitingTiles(tilesOf(c.concealed...), newCombinationCollection()); fmt.Sprint(waits) != fmt.Sprint(c.waits) {
+			t.Errorf("%s: expected waits %v, got %v", c.name, c.waits, waits)
+		}
+	}
+}
+
+func TestIsNineGates(t *testing.T) {
+	gates := tilesOf(Bamboo1, Bamboo1, Bamboo1, Bamboo2, Bamboo3, Bamboo4, Bamboo5, Bamboo6, Bamboo7, Bamboo8, Bamboo9, Bamboo9, Bamboo9, Bamboo2)
+	if !IsNineGates(gates, nil) {
+		t.Errorf("expected nine gates")
+	}
+
+	notGates := tilesOf(Bamboo1, Bamboo1, Bamboo1, Bamboo2, Bamboo3, Bamboo4, Bamboo5, Bamboo5, Bamboo7, Bamboo8, Bamboo9, Bamboo9, Bamboo9, Bamboo2)
+	if IsNineGates(notGates, nil) {
+		t.Errorf("expected a hand without a six not to be nine gates")
+	}
+}
+
+func TestCardMatches(t *testing.T) {
+	card, err := LoadCard("cards/sample.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	rules := AmericanRuleset(card)
+
+	cases := []struct {
+		concealed []Tile
+		tile      Tile
+		winning   bool
+	}{
+		// 222a 4444a 666a 8888a
+		{concealed: []Tile{Bamboo2, Bamboo2, Bamboo2, Bamboo4, Bamboo4, Bamboo4, Bamboo4, Bamboo6, Bamboo6, Bamboo6, Bamboo8, Bamboo8, Bamboo8}, tile: Bamboo8, winning: true},
+		{concealed: []Tile{Bamboo2, Bamboo2, Bamboo2, Bamboo4, Bamboo4, Bamboo4, Bamboo4, Bamboo6, Bamboo6, Bamboo6, Bamboo8, Bamboo8, Joker}, tile: Joker, winning: true},
+		{concealed: []Tile{Bamboo2, Bamboo2, Bamboo2, Bamboo4, Bamboo4, Bamboo4, Bamboo4, Bamboo6, Bamboo6, Bamboo6, Bamboo8, Bamboo8, Bamboo8}, tile: Circles8, winning: false},
+		// 11111a 2222b 33333c shifted, which needs jokers for the quints
+		{concealed: []Tile{Circles4, Circles4, Circles4, Circles4, Joker, Bamboo5, Bamboo5, Bamboo5, Bamboo5, Characters6, Characters6, Joker, Joker}, tile: Characters6, winning: true},
+		// FF 22a 4a 6a 88a 22b 44b 66b, jokers cannot be used in pairs and singles
+		{concealed: []Tile{FlowerPlumb, SeasonWinter, Circles2, Circles2, Circles4, Circles6, Circles8, Circles8, Bamboo2, Bamboo2, Bamboo4, Bamboo4, Bamboo6}, tile: Bamboo6, winning: true},
+		{concealed: []Tile{FlowerPlumb, SeasonWinter, Circles2, Circles2, Circles4, Circles6, Circles8, Circles8, Bamboo2, Bamboo2, Bamboo4, Bamboo4, Bamboo6}, tile: Joker, winning: false},
+	}
+
+	for _, c := range cases {
+		if rules.isWinningHand(tilesOf(c.concealed...), newCombinationCollection(), c.tile) != c.winning {
+			t.Errorf("expected hand %v with [%d] winning to be [%t]", c.concealed, c.tile, c.winning)
+		}
+	}
+
+	exposed := newCombinationCollection()
+	exposed.add(Pung{Tile: Bamboo2})
+	concealed := tilesOf(Bamboo4, Bamboo4, Bamboo4, Bamboo4, Bamboo6, Bamboo6, Bamboo6, Bamboo8, Bamboo8, Bamboo8)
+	if !rules.isWinningHand(concealed, exposed, Bamboo8) {
+		t.Errorf("expected an exposed pung to fill a group of the card")
+	}
+	exposed = newCombinationCollection()
+	exposed.add(Pung{Tile: Bamboo2})
+	concealed = tilesOf(Bamboo2, Bamboo2, Circles4, Circles6, Circles8, Circles8, Bamboo4, Bamboo4, Bamboo6, FlowerPlumb, SeasonWinter)
+	if rules.isWinningHand(concealed, exposed, Bamboo6) {
+		t.Errorf("expected a concealed hand on the card not to win with an exposed pung")
+	}
+}
+
+func TestCombinationsLeavesCollection(t *testing.T) {
+	exposed := newCombinationCollection()
+	exposed.add(Pung{Tile: RedDragon})
+	exposed.add(Chow{FirstTile: Bamboo1})
+
+	sorted := exposed.Combinations()
+	sorted[0] = Kong{Tile: EastWind}
+	if exposed.combinations[0] != (Pung{Tile: RedDragon}) || exposed.combinations[1] != (Chow{FirstTile: Bamboo1}) {
+		t.Errorf("expected reading the combinations not to change the collection, got %v", exposed.combinations)
+	}
+}
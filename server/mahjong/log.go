@@ -0,0 +1,200 @@
+package mahjong
+
+import (
+	"fmt"
+	"github.com/roelofruis/mahjong-learn/state"
+)
+
+// GameLog holds everything needed to play a game again exactly as it went: the ruleset and its options,
+// the seed, the order of the first wall and the actions selected in every transition.
+type GameLog struct {
+	// Name under which the ruleset is registered.
+	Ruleset        string      `json:"ruleset"`
+	Length         MatchLength `json:"length"`
+	BustEnds       bool        `json:"bust"`
+	OvertimeTarget int         `json:"overtime"`
+	HouseRules     []string    `json:"house_rules"`
+	Seed           int64       `json:"seed"`
+	// Tiles of the wall the first hand was dealt from, in the order they were shuffled.
+	Wall []Tile `json:"wall"`
+	// The action index selected per player, for every transition in order.
+	Actions []map[int]int `json:"actions"`
+	// New seeds the game continued with after it was cloned with a new seed, see Game.CloneWithSeed.
+	Reseeds []Reseed `json:"reseeds,omitempty"`
+}
+
+// Reseed is a new seed taken after a number of transitions.
+type Reseed struct {
+	Step int   `json:"step"`
+	Seed int64 `json:"seed"`
+}
+
+func newGameLog(table *Table) *GameLog {
+	rules := table.GetRuleset()
+	return &GameLog{
+		Ruleset:        rules.Name,
+		Length:         rules.Length,
+		BustEnds:       rules.BustEnds,
+		OvertimeTarget: rules.OvertimeTarget,
+		HouseRules:     rules.House.Names(),
+		Seed:           table.GetSeed(),
+		Wall:           table.wall.order(),
+		Actions:        make([]map[int]int, 0),
+	}
+}
+
+// The ruleset the game was played by, with the options of the log.
+func (l GameLog) rules() (Ruleset, error) {
+	rules, err := RulesetByName(l.Ruleset)
+	if err != nil {
+		return Ruleset{}, err
+	}
+	rules.Length = l.Length
+	rules.BustEnds = l.BustEnds
+	rules.OvertimeTarget = l.OvertimeTarget
+	for _, name := range l.HouseRules {
+		err = rules.House.Enable(name)
+		if err != nil {
+			return Ruleset{}, err
+		}
+	}
+	return rules, nil
+}
+
+// The log up to the given number of transitions.
+func (l GameLog) Truncate(steps int) GameLog {
+	if steps < len(l.Actions) {
+		l.Actions = l.Actions[:steps]
+	}
+	reseeds := make([]Reseed, 0, len(l.Reseeds))
+	for _, r := range l.Reseeds {
+		if r.Step <= steps {
+			reseeds = append(reseeds, r)
+		}
+	}
+	l.Reseeds = reseeds
+	return l
+}
+
+// A copy of the log that shares nothing with it.
+func (l GameLog) copy() GameLog {
+	l.Actions = append(make([]map[int]int, 0, len(l.Actions)), l.Actions...)
+	l.Reseeds = append([]Reseed(nil), l.Reseeds...)
+	return l
+}
+
+// Transition the game with the selected action per player, and record them in the log if they were valid.
+func (g *Game) Transition(selectedActions map[int]int) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	// the state machine ignores transitions once the game has ended, so they are not logged either
+	if g.StateMachine.HasTerminated() {
+		return nil
+	}
+
+	err := g.StateMachine.Transition(selectedActions)
+	if err != nil {
+		return err
+	}
+
+	actions := make(map[int]int, len(selectedActions))
+	for player, action := range selectedActions {
+		actions[player] = action
+	}
+	g.log.Actions = append(g.log.Actions, actions)
+
+	return nil
+}
+
+// Keep up to limit previous decision points, so that transitions can be undone.
+// Every transition saves a full copy of the table, so a game holds up to limit copies. This memory is spent
+// deliberately: a table is small, and putting back a whole copy is simpler and safer than undoing each change.
+func (g *Game) KeepHistory(limit int) {
+	g.StateMachine.KeepHistory(limit, func() func() {
+		saved := g.Table.clone()
+		return func() {
+			*g.Table = *saved
+		}
+	})
+}
+
+// Undo the given number of transitions, returning to an earlier decision point. The undone actions, and the seeds
+// taken after them, are removed from the log.
+// Nothing is undone if the history does not go back that far.
+func (g *Game) Undo(steps int) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if steps > g.StateMachine.HistorySize() {
+		return fmt.Errorf("cannot undo [%d] transitions, only [%d] are kept", steps, g.StateMachine.HistorySize())
+	}
+
+	for i := 0; i < steps; i++ {
+		err := g.StateMachine.Undo()
+		if err != nil {
+			return err
+		}
+	}
+	*g.log = g.log.Truncate(len(g.log.Actions) - steps)
+	return nil
+}
+
+// The log of the game so far.
+func (g *Game) Log() GameLog {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.log.copy()
+}
+
+// Replay rebuilds the game the log was taken from, at the last step in the log.
+// The ruleset has to be registered under the name in the log. Use Truncate to rebuild the game at an earlier step.
+func Replay(log GameLog) (*Game, error) {
+	rules, err := log.rules()
+	if err != nil {
+		return nil, err
+	}
+
+	game, err := NewSeededGame(rules, log.Seed, &state.ProductionTransitioner{IntermediateTransitionLimit: 10})
+	if err != nil {
+		return nil, err
+	}
+
+	if !sameOrder(game.log.Wall, log.Wall) {
+		return nil, fmt.Errorf("the seed [%d] does not give the wall in the log", log.Seed)
+	}
+
+	for i, actions := range log.Actions {
+		game.reseedAt(log.Reseeds, i)
+		err = game.Transition(actions)
+		if err != nil {
+			return nil, fmt.Errorf("replaying transition [%d] failed: %s", i, err.Error())
+		}
+	}
+	game.reseedAt(log.Reseeds, len(log.Actions))
+
+	return game, nil
+}
+
+// Continue with the new seeds that were taken after the given number of transitions, and record them in the log.
+func (g *Game) reseedAt(reseeds []Reseed, step int) {
+	for _, r := range reseeds {
+		if r.Step == step {
+			g.Table.reseed(r.Seed)
+			g.log.Reseeds = append(g.log.Reseeds, r)
+		}
+	}
+}
+
+func sameOrder(a []Tile, b []Tile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
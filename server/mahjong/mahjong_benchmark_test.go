@@ -11,7 +11,7 @@ func BenchmarkGame(b *testing.B) {
 
 	for i := 0; i < b.N; i++ {
 		b.StopTimer()
-		game, _ := NewGame(&state.ProductionTransitioner{IntermediateTransitionLimit: 10})
+		game, _ := NewGame(ClassicalRuleset(), &state.ProductionTransitioner{IntermediateTransitionLimit: 10})
 
 		b.StartTimer()
 		for {
@@ -28,3 +28,21 @@ func BenchmarkGame(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkClone(b *testing.B) {
+	rand.Seed(0)
+
+	game, _ := NewSeededGame(ClassicalRuleset(), 0, &state.ProductionTransitioner{IntermediateTransitionLimit: 10})
+	for i := 0; i < 50 && !game.StateMachine.HasTerminated(); i++ {
+		selectedActions := make(map[int]int)
+		for player, a := range game.StateMachine.AvailableActions() {
+			selectedActions[player] = rand.Intn(len(a))
+		}
+		_ = game.Transition(selectedActions)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = game.Clone()
+	}
+}
@@ -1,15 +1,17 @@
 package mahjong
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/roelofruis/mahjong-learn/state"
+	"io/ioutil"
 	"math/rand"
 	"strings"
 	"testing"
 )
 
 func TestRun(t *testing.T) {
-	transitioner, err := runGame()
+	transitioner, err := runGame(ClassicalRuleset())
 	if err != nil {
 		t.Logf("err: %s", err)
 		t.Logf("%s\n", describeState(transitioner))
@@ -21,7 +23,7 @@ func TestRun(t *testing.T) {
 
 func Test1kRuns(t *testing.T) {
 	for i := 1000; i > 0; i-- {
-		transitioner, err := runGame()
+		transitioner, err := runGame(ClassicalRuleset())
 		if err != nil {
 			t.Logf("err: %s", err)
 			t.Logf("%s\n", describeState(transitioner))
@@ -32,9 +34,421 @@ func Test1kRuns(t *testing.T) {
 	t.Logf("ran 1000 games without errors")
 }
 
-func runGame() (*state.DebugTransitioner, error) {
+func TestSeededGamesRepeat(t *testing.T) {
+	for _, name := range []string{"classical", "riichi", "sichuan"} {
+		rules, _ := RulesetByName(name)
+		first, _ := NewSeededGame(rules, 42, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+		second, _ := NewSeededGame(rules, 42, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+		choices := rand.New(rand.NewSource(7))
+
+		for !first.StateMachine.HasTerminated() {
+			if !sameTable(first.Table, second.Table) {
+				t.Fatalf("ruleset [%s]: expected games with the same seed and actions to be identical", name)
+			}
+
+			selectedActions := make(map[int]int)
+			for player, a := range first.StateMachine.AvailableActions() {
+				selectedActions[player] = choices.Intn(len(a))
+			}
+			if err := first.StateMachine.Transition(selectedActions); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if err := second.StateMachine.Transition(selectedActions); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+	}
+}
+
+func sameTable(a *Table, b *Table) bool {
+	if fmt.Sprint(a.wall.live, a.wall.dead, a.GetHandsPlayed()) != fmt.Sprint(b.wall.live, b.wall.dead, b.GetHandsPlayed()) {
+		return false
+	}
+	for s := range a.players {
+		pa, pb := a.players[s], b.players[s]
+		if pa.score != pb.score || fmt.Sprint(pa.concealed.list(), pa.discards) != fmt.Sprint(pb.concealed.list(), pb.discards) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReplay(t *testing.T) {
+	for _, name := range []string{"classical", "riichi", "sichuan"} {
+		rules, _ := RulesetByName(name)
+		rules.House.NoChowClaims = true
+		game, _ := NewGame(rules, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+		for !game.StateMachine.HasTerminated() {
+			selectedActions := make(map[int]int)
+			for player, a := range game.StateMachine.AvailableActions() {
+				selectedActions[player] = rand.Intn(len(a))
+			}
+			if err := game.Transition(selectedActions); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+
+		data, err := json.Marshal(game.Log())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		var log GameLog
+		if err = json.Unmarshal(data, &log); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		replayed, err := Replay(log)
+		if err != nil {
+			t.Fatalf("ruleset [%s]: unexpected error: %s", name, err.Error())
+		}
+		if !replayed.StateMachine.HasTerminated() || !sameTable(game.Table, replayed.Table) {
+			t.Errorf("ruleset [%s]: expected the replayed game to be identical", name)
+		}
+
+		halfway, err := Replay(log.Truncate(len(log.Actions) / 2))
+		if err != nil {
+			t.Fatalf("ruleset [%s]: unexpected error: %s", name, err.Error())
+		}
+		for _, actions := range log.Actions[len(log.Actions)/2:] {
+			if err = halfway.Transition(actions); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+		if !sameTable(game.Table, halfway.Table) {
+			t.Errorf("ruleset [%s]: expected the game replayed halfway and played on to be identical", name)
+		}
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	for _, name := range []string{"classical", "riichi", "sichuan"} {
+		rules, _ := RulesetByName(name)
+		game, _ := NewGame(rules, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+
+		for step := 0; !game.StateMachine.HasTerminated(); step++ {
+			if step%50 == 0 {
+				data, err := json.Marshal(game.Snapshot())
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+				var snapshot Snapshot
+				if err = json.Unmarshal(data, &snapshot); err != nil {
+					t.Fatalf("unexpected error: %s", err.Error())
+				}
+				restored, err := Restore(snapshot)
+				if err != nil {
+					t.Fatalf("ruleset [%s]: unexpected error: %s", name, err.Error())
+				}
+				if restored.StateMachine.StateName() != game.StateMachine.StateName() || !sameTable(game.Table, restored.Table) {
+					t.Fatalf("ruleset [%s]: expected the restored game to be identical", name)
+				}
+				if fmt.Sprint(restored.StateMachine.AvailableActions()) != fmt.Sprint(game.StateMachine.AvailableActions()) {
+					t.Fatalf("ruleset [%s]: expected the restored game to offer the same actions", name)
+				}
+				game = restored
+			}
+
+			selectedActions := make(map[int]int)
+			for player, a := range game.StateMachine.AvailableActions() {
+				selectedActions[player] = rand.Intn(len(a))
+			}
+			if err := game.Transition(selectedActions); err != nil {
+				t.Fatalf("ruleset [%s]: unexpected error: %s", name, err.Error())
+			}
+			if err := checkInvariants(*game.Table, rules.tileSet().Size()); err != nil {
+				t.Fatalf("ruleset [%s]: invariant failed after restoring: %s", name, err.Error())
+			}
+		}
+
+		replayed, err := Replay(game.Log())
+		if err != nil {
+			t.Fatalf("ruleset [%s]: unexpected error: %s", name, err.Error())
+		}
+		if !sameTable(game.Table, replayed.Table) {
+			t.Errorf("ruleset [%s]: expected a game that was restored along the way to match its replay", name)
+		}
+	}
+}
+
+func TestRestoreStates(t *testing.T) {
+	card, err := LoadCard("cards/sample.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	RegisterRuleset("american", func() Ruleset { return AmericanRuleset(card) })
+
+	ended, _ := NewGame(ClassicalRuleset(), &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+	playRandomly(t, ended, -1)
+	sichuan, _ := NewGame(SichuanRuleset(), &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+	american, _ := NewGame(AmericanRuleset(card), &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+
+	for _, game := range []*Game{gameInState(t, "Kong Added"), ended, sichuan, american} {
+		name := game.StateMachine.StateName()
+		restored := restoreThroughJSON(t, game)
+		if restored.StateMachine.StateName() != name || !sameTable(game.Table, restored.Table) {
+			t.Fatalf("state [%s]: expected the restored game to be identical", name)
+		}
+		if fmt.Sprint(restored.StateMachine.AvailableActions()) != fmt.Sprint(game.StateMachine.AvailableActions()) {
+			t.Fatalf("state [%s]: expected the restored game to offer the same actions", name)
+		}
+		playRandomly(t, restored, -1)
+	}
+}
+
+func TestRestoreRejectsInvalidSnapshots(t *testing.T) {
+	game, _ := NewSeededGame(ClassicalRuleset(), 42, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+	playRandomly(t, game, 10)
+
+	tile := Bamboo1
+	cases := map[string]func(s *Snapshot){
+		"active player out of range": func(s *Snapshot) { s.Table.ActivePlayer = 4 },
+		"dealer out of range":        func(s *Snapshot) { s.Table.Dealer = -1 },
+		"missing player":             func(s *Snapshot) { s.Table.Players = s.Table.Players[:3] },
+		"unknown tile in the wall":   func(s *Snapshot) { s.Table.Wall.Live[0].Tile = 99 },
+		"joker outside its set":      func(s *Snapshot) { s.Table.Players[1].Concealed.add(Joker) },
+		"chow beyond the suit":       func(s *Snapshot) { s.Table.Players[1].Exposed.add(Chow{FirstTile: Bamboo9}) },
+		"discard of unknown tile":    func(s *Snapshot) { s.Table.Players[1].Discards = []DiscardedTile{{Tile: -3, ClaimedBy: -1}} },
+		"received while not active":  func(s *Snapshot) { s.Table.Players[(s.Table.ActivePlayer+1)%4].Received = &tile },
+		"state without its tile":     func(s *Snapshot) { s.State, s.Table.ActiveDiscard = "Tile Discarded", nil },
+		"tile created":               func(s *Snapshot) { s.Table.Players[1].Discarded.add(Bamboo1) },
+		"tile lost":                  func(s *Snapshot) { s.Table.Wall.Live = s.Table.Wall.Live[1:] },
+	}
+	for name, corrupt := range cases {
+		snapshot := game.Snapshot()
+		data, _ := json.Marshal(snapshot)
+		_ = json.Unmarshal(data, &snapshot)
+		corrupt(&snapshot)
+		if _, err := Restore(snapshot); err == nil {
+			t.Errorf("%s: expected the snapshot to be rejected", name)
+		}
+	}
+}
+
+// A classical game played randomly until it reaches the state with the given name.
+func gameInState(t *testing.T, name string) *Game {
+	for seed := int64(0); seed < 1000; seed++ {
+		game, _ := NewSeededGame(ClassicalRuleset(), seed, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+		for !game.StateMachine.HasTerminated() {
+			if game.StateMachine.StateName() == name {
+				return game
+			}
+			playRandomly(t, game, 1)
+		}
+	}
+	t.Fatalf("no game reached state [%s]", name)
+	return nil
+}
+
+func restoreThroughJSON(t *testing.T, game *Game) *Game {
+	data, err := json.Marshal(game.Snapshot())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var snapshot Snapshot
+	if err = json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	restored, err := Restore(snapshot)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	return restored
+}
+
+func TestClone(t *testing.T) {
+	for _, name := range []string{"classical", "riichi", "sichuan"} {
+		rules, _ := RulesetByName(name)
+		game, _ := NewGame(rules, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+		playRandomly(t, game, 40)
+
+		rollout := game.Clone()
+		if rollout.StateMachine.Transitioner() == game.StateMachine.Transitioner() {
+			t.Fatalf("ruleset [%s]: expected the clone to get its own transitioner", name)
+		}
+		untouched := game.Clone()
+		playRandomly(t, rollout, -1)
+		if !sameTable(game.Table, untouched.Table) || game.StateMachine.StateName() != untouched.StateMachine.StateName() {
+			t.Fatalf("ruleset [%s]: expected playing a clone not to affect the original", name)
+		}
+
+		for !game.StateMachine.HasTerminated() {
+			selectedActions := make(map[int]int)
+			for player, a := range game.StateMachine.AvailableActions() {
+				selectedActions[player] = rand.Intn(len(a))
+			}
+			if err := game.Transition(selectedActions); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if err := untouched.Transition(selectedActions); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+		if !sameTable(game.Table, untouched.Table) {
+			t.Errorf("ruleset [%s]: expected a clone to play out the same as the original given the same actions", name)
+		}
+	}
+}
+
+func TestCloneStates(t *testing.T) {
+	ended, _ := NewGame(ClassicalRuleset(), &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+	playRandomly(t, ended, -1)
+
+	for _, game := range []*Game{gameInState(t, "Kong Added"), ended} {
+		name := game.StateMachine.StateName()
+		clone := game.Clone()
+		if clone.StateMachine.StateName() != name || clone.StateMachine.HasTerminated() != game.StateMachine.HasTerminated() {
+			t.Fatalf("state [%s]: expected the clone to be in the same state, got [%s]", name, clone.StateMachine.StateName())
+		}
+		if fmt.Sprint(clone.StateMachine.AvailableActions()) != fmt.Sprint(game.StateMachine.AvailableActions()) {
+			t.Fatalf("state [%s]: expected the clone to offer the same actions", name)
+		}
+		untouched := game.Clone()
+		playRandomly(t, clone, -1)
+		if !sameTable(game.Table, untouched.Table) {
+			t.Errorf("state [%s]: expected playing a clone not to affect the original", name)
+		}
+	}
+}
+
+func TestCloneWithSeed(t *testing.T) {
+	game, _ := NewSeededGame(ClassicalRuleset(), 42, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+	playRandomly(t, game, 10)
+
+	first := game.CloneWithSeed(1)
+	second := game.CloneWithSeed(2)
+	if first.Table.GetSeed() != 1 || fmt.Sprint(first.Table.wall.live) == fmt.Sprint(second.Table.wall.live) {
+		t.Errorf("expected clones with different seeds to shuffle the live wall differently")
+	}
+	if first.Table.GetWall().Tiles().Size() != game.Table.GetWall().Tiles().Size() {
+		t.Errorf("expected the clone to keep the tiles of the wall")
+	}
+	playRandomly(t, first, -1)
+
+	replayed, err := Replay(first.Log())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if !sameTable(first.Table, replayed.Table) {
+		t.Errorf("expected a clone with a new seed to match its replay")
+	}
+	if len(first.Log().Truncate(5).Reseeds) != 0 {
+		t.Errorf("expected truncating the log before the new seed to drop it")
+	}
+}
+
+func TestUndo(t *testing.T) {
+	for _, name := range []string{"classical", "riichi", "sichuan"} {
+		rules, _ := RulesetByName(name)
+		game, _ := NewGame(rules, &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000})
+		game.KeepHistory(5)
+		playRandomly(t, game, 20)
+
+		before := game.Clone()
+		actions := len(game.Log().Actions)
+		playRandomly(t, game, 3)
+		game.log.Reseeds = append(game.log.Reseeds, Reseed{Step: actions + 1, Seed: 1})
+
+		if err := game.Undo(3); err != nil {
+			t.Fatalf("ruleset [%s]: unexpected error: %s", name, err.Error())
+		}
+		if !sameTable(game.Table, before.Table) || game.StateMachine.StateName() != before.StateMachine.StateName() {
+			t.Fatalf("ruleset [%s]: expected undo to return to the earlier decision point", name)
+		}
+		if fmt.Sprint(game.StateMachine.AvailableActions()) != fmt.Sprint(before.StateMachine.AvailableActions()) {
+			t.Fatalf("ruleset [%s]: expected undo to offer the earlier actions again", name)
+		}
+		if len(game.Log().Actions) != actions || len(game.Log().Reseeds) != 0 {
+			t.Errorf("ruleset [%s]: expected the undone actions and seeds to be removed from the log", name)
+		}
+
+		if game.Undo(3) == nil {
+			t.Errorf("ruleset [%s]: expected an error when undoing beyond the kept history", name)
+		}
+
+		playRandomly(t, game, -1)
+		actions = len(game.Log().Actions)
+		if err := game.Transition(map[int]int{}); err != nil || len(game.Log().Actions) != actions {
+			t.Errorf("ruleset [%s]: expected a transition after the game ended not to be logged", name)
+		}
+		replayed, err := Replay(game.Log())
+		if err != nil || !sameTable(game.Table, replayed.Table) {
+			t.Errorf("ruleset [%s]: expected a game with undone transitions to match its replay", name)
+		}
+	}
+}
+
+// Play random actions for the given number of transitions, or until the game ends if it is negative.
+func playRandomly(t *testing.T, game *Game, transitions int) {
+	for i := 0; i != transitions && !game.StateMachine.HasTerminated(); i++ {
+		selectedActions := make(map[int]int)
+		for player, a := range game.StateMachine.AvailableActions() {
+			selectedActions[player] = rand.Intn(len(a))
+		}
+		if err := game.Transition(selectedActions); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if err := checkInvariants(*game.Table, game.Table.GetRuleset().tileSet().Size()); err != nil {
+			t.Fatalf("invariant failed: %s", err.Error())
+		}
+	}
+}
+
+func TestRulesetRuns(t *testing.T) {
+	for _, name := range RulesetNames() {
+		rules, _ := RulesetByName(name)
+		for i := 100; i > 0; i-- {
+			transitioner, err := runGame(rules)
+			if err != nil {
+				t.Logf("ruleset [%s] err: %s", name, err)
+				t.Logf("%s\n", describeState(transitioner))
+				t.FailNow()
+			}
+		}
+	}
+
+	t.Logf("ran 100 games per ruleset without errors")
+}
+
+func TestHouseRuleRuns(t *testing.T) {
+	rules := ClassicalRuleset()
+	for _, name := range []string{"no-chow", "no-pung", "no-kong", "no-concealed-kong", "no-bonus-tiles", "no-interrupts"} {
+		_ = rules.House.Enable(name)
+	}
+
+	for i := 100; i > 0; i-- {
+		transitioner, err := runGame(rules)
+		if err != nil {
+			t.Logf("err: %s", err)
+			t.Logf("%s\n", describeState(transitioner))
+			t.FailNow()
+		}
+	}
+
+	t.Logf("ran 100 games with all house rules without errors")
+}
+
+func TestAmericanRuns(t *testing.T) {
+	card, err := LoadCard("cards/sample.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for i := 100; i > 0; i-- {
+		transitioner, err := runGame(AmericanRuleset(card))
+		if err != nil {
+			t.Logf("err: %s", err)
+			t.Logf("%s\n", describeState(transitioner))
+			t.FailNow()
+		}
+	}
+
+	t.Logf("ran 100 games with the sample card without errors")
+}
+
+func runGame(rules Ruleset) (*state.DebugTransitioner, error) {
 	transitioner := &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 1000000}
-	game, _ := NewGame(transitioner)
+	game, _ := NewGame(rules, transitioner)
+	tileCount := rules.tileSet().Size()
 
 	numTransitions := 0
 	var stateHistory []string
@@ -56,39 +470,74 @@ func runGame() (*state.DebugTransitioner, error) {
 			selectedActions[player] = rand.Intn(len(a))
 		}
 
-		err := game.StateMachine.Transition(selectedActions)
+		err := game.Transition(selectedActions)
 		if err != nil {
-			return transitioner, fmt.Errorf("game transition raised an error: %s", err.Error())
+			return transitioner, fmt.Errorf("game transition raised an error: %s%s", err.Error(), saveFailedLog(game))
 		}
 
 		numTransitions++
 
-		err = checkInvariants(*game.Table)
+		err = checkInvariants(*game.Table, tileCount)
 		if err != nil {
-			return transitioner, fmt.Errorf("invariant failed after [%d] transitions: %s", numTransitions, err.Error())
+			return transitioner, fmt.Errorf("invariant failed after [%d] transitions: %s%s", numTransitions, err.Error(), saveFailedLog(game))
 		}
 	}
 	return nil, nil
 }
 
-func checkInvariants(table Table) error {
-	return checkTileCount(table)
+// Write the log of a failed game to a temporary file, so the failure can be reproduced with Replay.
+func saveFailedLog(game *Game) string {
+	data, err := json.Marshal(game.Log())
+	if err != nil {
+		return ""
+	}
+	file, err := ioutil.TempFile("", "mahjong-failed-*.json")
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	if _, err = file.Write(data); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\nthe game log is written to [%s]", file.Name())
+}
+
+func checkInvariants(table Table, tiles int) error {
+	err := checkTileCount(table, tiles)
+	if err != nil {
+		return err
+	}
+	return checkScoreSum(table)
+}
+
+func checkScoreSum(table Table) error {
+	sum := table.GetDeposits()
+	for p := 0; p < table.GetPlayerCount(); p++ {
+		sum += table.GetPlayerByIndex(p).GetScore() - table.GetRuleset().StartingScore
+	}
+
+	if sum != 0 {
+		return fmt.Errorf("scores and deposits do not add up to the starting scores but differ by [%d]", sum)
+	}
+
+	return nil
 }
 
-func checkTileCount(table Table) error {
+func checkTileCount(table Table, tiles int) error {
 	wall := table.GetWall().Size()
 	discard := 0
 	if table.GetActiveDiscard() != nil {
 		discard = 1
 	}
-	player1 := countPlayerTiles(table.GetPlayerByIndex(0))
-	player2 := countPlayerTiles(table.GetPlayerByIndex(1))
-	player3 := countPlayerTiles(table.GetPlayerByIndex(2))
-	player4 := countPlayerTiles(table.GetPlayerByIndex(3))
-
-	tileCount := wall + discard + player1 + player2 + player3 + player4
+	if table.GetAddedKongTile() != nil {
+		discard++
+	}
+	tileCount := wall + discard
+	for p := 0; p < table.GetPlayerCount(); p++ {
+		tileCount += countPlayerTiles(table.GetPlayerByIndex(p))
+	}
 
-	if tileCount != 144 {
+	if tileCount != tiles {
 		return fmt.Errorf("incorrect tile count [%d]", tileCount)
 	}
 
@@ -134,6 +583,8 @@ func describeAction(action state.Action) string {
 		return fmt.Sprintf("Discard [%d]", a.Tile)
 	case DeclareConcealedKong:
 		return fmt.Sprintf("Declare a concealed Kong [%d]", a.Tile)
+	case DeclareRiichi:
+		return fmt.Sprintf("Declare riichi [%d]", a.Tile)
 	case ExposedPungToKong:
 		return fmt.Sprintf("Add to exposed pung")
 	case DoNothing:
@@ -146,6 +597,10 @@ func describeAction(action state.Action) string {
 		return "Declare a kong"
 	case DeclareMahjong:
 		return "Declare mahjong"
+	case DeclareMissingSuit:
+		return fmt.Sprintf("Declare missing suit [%d]", a.Suit)
+	case PassTiles:
+		return fmt.Sprintf("Pass tiles %v", a.Tiles)
 	}
 	return "<UNKNOWN>"
 }
@@ -1,7 +1,5 @@
 package mahjong
 
-import "sort"
-
 type Player struct {
 	score int
 
@@ -10,6 +8,39 @@ type Player struct {
 	concealed *TileCollection
 	exposed   *CombinationCollection
 	discarded *TileCollection
+	// Every tile the player discarded this round in order, including the ones claimed by other players.
+	discards []DiscardedTile
+	// Whether the player let a winning tile pass since their last discard, or since declaring riichi.
+	passedOnWin bool
+
+	// Whether and how the player declared riichi this round.
+	riichi RiichiStatus
+	// Whether the player declared riichi less than a go-around ago, without any claims in between.
+	ippatsu bool
+	// Number of red fives in the concealed tiles, including the received tile, per five.
+	redFives map[Tile]int
+	// Number of red fives in exposed combinations.
+	exposedRedFives int
+
+	// First tile of the suit the player declared to get rid of, zero if the ruleset has no missing suit.
+	missingSuit Tile
+	// Whether the player won and left the hand, in rulesets where the others play on after a win.
+	won bool
+}
+
+type RiichiStatus int
+
+const (
+	RiichiNone RiichiStatus = iota
+	RiichiDeclared
+	// Riichi declared with the first discard of the round, before any claims were made.
+	RiichiDoubleDeclared
+)
+
+type DiscardedTile struct {
+	Tile Tile
+	// Seat of the player that claimed the tile, or -1 if nobody did.
+	ClaimedBy int
 }
 
 func newPlayer(wind Wind) *Player {
@@ -20,6 +51,17 @@ func newPlayer(wind Wind) *Player {
 		concealed: newEmptyTileCollection(),
 		exposed:   newCombinationCollection(),
 		discarded: newEmptyTileCollection(),
+		discards:  make([]DiscardedTile, 0),
+
+		passedOnWin: false,
+
+		riichi:          RiichiNone,
+		ippatsu:         false,
+		redFives:        make(map[Tile]int),
+		exposedRedFives: 0,
+
+		missingSuit: 0,
+		won:         false,
 	}
 }
 
@@ -34,9 +76,7 @@ func (p *Player) GetWind() Wind {
 }
 
 func (p *Player) GetExposedCombinations() []Combination {
-	sort.Sort(ByCombinationOrder(p.exposed.combinations))
-
-	return p.exposed.combinations
+	return p.exposed.Combinations()
 }
 
 func (p *Player) GetExposedCombinationCollection() *CombinationCollection {
@@ -47,6 +87,11 @@ func (p *Player) GetDiscardedTiles() *TileCollection {
 	return p.discarded
 }
 
+// All tiles the player discarded this round in order, including the ones that were claimed.
+func (p *Player) GetDiscardHistory() []DiscardedTile {
+	return p.discards
+}
+
 func (p *Player) GetReceivedTile() *Tile {
 	return p.received
 }
@@ -54,3 +99,128 @@ func (p *Player) GetReceivedTile() *Tile {
 func (p *Player) GetScore() int {
 	return p.score
 }
+
+func (p *Player) GetRiichi() RiichiStatus {
+	return p.riichi
+}
+
+// Number of red fives the player holds, both concealed and exposed.
+func (p *Player) GetRedFives() int {
+	red := p.exposedRedFives
+	for _, n := range p.redFives {
+		red += n
+	}
+	return red
+}
+
+// The first tile of the declared missing suit, zero if none was declared.
+func (p *Player) GetMissingSuit() Tile {
+	return p.missingSuit
+}
+
+// Whether the player won and left the hand.
+func (p *Player) HasWon() bool {
+	return p.won
+}
+
+// Whether the tile belongs to the suit the player declared missing.
+func (p *Player) isMissingSuit(tile Tile) bool {
+	return p.missingSuit != 0 && tile.IsSuit() && tile.Suit() == p.missingSuit
+}
+
+// Whether the player still holds tiles of the declared missing suit, which have to be discarded before anything else.
+func (p *Player) holdsMissingSuit() bool {
+	if p.received != nil && p.isMissingSuit(*p.received) {
+		return true
+	}
+	for tile, count := range p.concealed.tiles {
+		if count > 0 && p.isMissingSuit(tile) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the player has not claimed any discards this round. Concealed kongs and bonus tiles keep the hand concealed.
+func (p *Player) hasConcealedHand() bool {
+	for _, c := range p.exposed.combinations {
+		switch comb := c.(type) {
+		case BonusTile:
+			continue
+		case Kong:
+			if comb.Concealed {
+				continue
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Whether a single tile would complete the hand.
+// A player that still holds tiles of the missing suit cannot win, so is never one tile away.
+func (p *Player) isTenpai(rules Ruleset) bool {
+	return !p.holdsMissingSuit() && len(rules.WaitingTiles(p.concealed, p.exposed)) > 0
+}
+
+// Whether the player may not win on a discard, because they discarded one of the tiles they are waiting on,
+// or let a winning tile pass since their last discard.
+func (p *Player) isFuriten(rules Ruleset) bool {
+	if p.passedOnWin {
+		return true
+	}
+
+	for _, wait := range rules.WaitingTiles(p.concealed, p.exposed) {
+		for _, d := range p.discards {
+			if d.Tile == wait {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Take n fives of the given kind out of the concealed tiles, including the received tile, and return how many of them are red.
+// Regular fives are always given up before red ones. Should be called before the tiles are removed.
+func (p *Player) giveUpFives(tile Tile, n int) int {
+	red := p.redFives[tile]
+	if red == 0 {
+		return 0
+	}
+
+	held := p.concealed.NumOf(tile)
+	if p.received != nil && *p.received == tile {
+		held++
+	}
+
+	redGivenUp := n - (held - red)
+	if redGivenUp < 0 {
+		redGivenUp = 0
+	}
+	if redGivenUp > red {
+		redGivenUp = red
+	}
+
+	p.redFives[tile] -= redGivenUp
+	return redGivenUp
+}
+
+// Whether a player in riichi can declare a concealed kong of the received tile, which is only allowed if it does not change the waits.
+func (p *Player) kongKeepsWaits(tile Tile, rules Ruleset) bool {
+	before := rules.WaitingTiles(p.concealed, p.exposed)
+
+	hand := p.concealed.copy()
+	hand.removeAll(tile)
+	after := rules.WaitingTiles(hand, p.exposed)
+
+	if len(before) != len(after) {
+		return false
+	}
+	for i := range before {
+		if before[i] != after[i] {
+			return false
+		}
+	}
+	return true
+}
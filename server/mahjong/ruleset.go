@@ -0,0 +1,659 @@
+package mahjong
+
+import (
+	"fmt"
+	"github.com/roelofruis/mahjong-learn/state"
+	"math/rand"
+	"sort"
+)
+
+// Ruleset holds the choices that differ between the variants of the game.
+type Ruleset struct {
+	// Name under which the ruleset is registered.
+	Name string
+	// Number of players at the table.
+	Players int
+	// The tiles the wall is built from.
+	Tiles TileSet
+	// Number of tiles dealt to each player, not counting bonus tiles.
+	HandSize int
+	// The claims players can make on a discarded tile, from highest to lowest priority. Claims that are left out cannot be made.
+	Claims []Claim
+	// How many hands are played before the game ends.
+	Length MatchLength
+	// Whether the game ends as soon as a player's score drops below zero.
+	BustEnds bool
+	// Score a player needs at the end of the match. If nobody has it, the game goes on into the next prevalent wind
+	// and ends after the first hand in which someone reaches it, at the latest after the North round. Zero for no overtime.
+	OvertimeTarget int
+	// Score every player starts the game with.
+	StartingScore int
+	// Values winning hands and settles the payments.
+	Scorer Scorer
+	// Whether other players can see the tile a concealed kong is made of.
+	RevealConcealedKongs bool
+	// When the dealer keeps the seat for another hand.
+	DealerRetention DealerRetention
+	// What happens when several players claim the same tile to win.
+	MultipleWins MultipleWinPolicy
+	// Whether players that discarded one of their winning tiles, or let one pass, may not win on a discard.
+	Furiten bool
+	// Which irregular hands count as a win.
+	SpecialHands SpecialHands
+	// Hands a winning hand has to match instead of four sets and a pair, nil if the ruleset does not use a card.
+	Card *Card
+	// Whether players with a concealed hand can declare riichi when they are one tile away from winning.
+	Riichi bool
+	// Amount a player puts on the table when declaring riichi, which goes to the next winner.
+	RiichiDeposit int
+	// Number of dora indicators set aside from the dead wall, zero if the ruleset does not use dora.
+	// The same number of ura dora indicators is set aside below them. The first indicator is revealed at the start, every kong reveals one more.
+	DoraIndicators int
+	// Mechanics that are switched off on top of the ruleset, to play a simplified game.
+	House HouseRules
+
+	// Replaces states of the standard flow, nil if the ruleset uses the standard flow.
+	customizeStates func(states *stateSet)
+}
+
+// The states a game by this ruleset moves through.
+func (r Ruleset) states() stateSet {
+	states := standardStates()
+	if r.customizeStates != nil {
+		r.customizeStates(&states)
+	}
+	return states.recordingWaits()
+}
+
+// TileSet describes the tiles a game is played with.
+type TileSet struct {
+	// Whether the dragons and winds are part of the set.
+	Honors bool
+	// Whether the flowers and seasons are part of the set.
+	BonusTiles bool
+	// Whether the flowers and seasons are kept in the hand like other tiles, instead of being set aside.
+	BonusTilesInHand bool
+	// Number of jokers in the set.
+	Jokers int
+	// Tiles that are left out of the set entirely.
+	Excluded []Tile
+	// Honor tiles that are set aside as bonus tiles when drawn, like the flowers and seasons.
+	BonusHonors []Tile
+	// Number of fives in each suit that are red.
+	RedFives int
+}
+
+// All tiles in the set.
+func (s TileSet) collection() *TileCollection {
+	set := newMahjongSet()
+	for _, tile := range allTileKinds() {
+		if tile.IsHonor() && !s.Honors {
+			set.removeAll(tile)
+		}
+	}
+	if !s.BonusTiles {
+		for tile := FlowerPlumb; tile <= SeasonWinter; tile++ {
+			set.removeAll(tile)
+		}
+	}
+	for _, tile := range s.Excluded {
+		set.removeAll(tile)
+	}
+	for i := 0; i < s.Jokers; i++ {
+		set.add(Joker)
+	}
+	return set
+}
+
+// Number of tiles in the set.
+func (s TileSet) Size() int {
+	return s.collection().Size()
+}
+
+// Whether the tile is set aside as a bonus tile when drawn.
+func (s TileSet) isBonusTile(tile Tile) bool {
+	if tile.IsBonusTile() {
+		return !s.BonusTilesInHand
+	}
+	for _, honor := range s.BonusHonors {
+		if tile == honor {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the tile is part of the set.
+func (s TileSet) contains(tile Tile) bool {
+	if tile.IsHonor() && !s.Honors {
+		return false
+	}
+	for _, excluded := range s.Excluded {
+		if tile == excluded {
+			return false
+		}
+	}
+	return true
+}
+
+// The dora the indicator points at, skipping tiles that are not part of the set.
+func (s TileSet) doraFromIndicator(indicator Tile) Tile {
+	dora := indicator.DoraFromIndicator()
+	for !s.contains(dora) {
+		dora = dora.DoraFromIndicator()
+	}
+	return dora
+}
+
+// The tiles the wall is built from, without bonus tiles if the house rules leave them out.
+func (r Ruleset) tileSet() TileSet {
+	tiles := r.Tiles
+	if r.House.NoBonusTiles {
+		tiles.BonusTiles = false
+		tiles.BonusHonors = nil
+	}
+	return tiles
+}
+
+// A new wall for a round played by this ruleset, shuffled by rng.
+func (r Ruleset) newWall(rng *rand.Rand) *Wall {
+	tiles := r.tileSet()
+	wall := newWall(tiles.collection(), tiles.RedFives, rng)
+	wall.setAsideIndicators(2 * r.DoraIndicators)
+	return wall
+}
+
+// Claim is a way of claiming a discarded tile.
+type Claim int
+
+const (
+	ClaimChow Claim = iota
+	ClaimPung
+	ClaimKong
+	ClaimMahjong
+)
+
+// HouseRules switch off mechanics of a ruleset, so agents can learn a stripped-down game first
+// and get the mechanics back one at a time. The zero value switches off nothing.
+type HouseRules struct {
+	NoChowClaims bool
+	NoPungClaims bool
+	// Also stops players from adding a tile to an exposed pung.
+	NoKongClaims     bool
+	NoConcealedKongs bool
+	// The flowers and seasons are left out of the wall, and honors that would be set aside as bonus tiles are played as normal tiles.
+	NoBonusTiles bool
+	// Only the next player in turn can claim a discard for a set, so claims never skip a player. Claiming a discard to win is still allowed.
+	NoClaimInterrupts bool
+}
+
+// The names of the house rules, as used in requests and views.
+var houseRuleNames = []struct {
+	name   string
+	toggle func(h *HouseRules) *bool
+}{
+	{"no-chow", func(h *HouseRules) *bool { return &h.NoChowClaims }},
+	{"no-pung", func(h *HouseRules) *bool { return &h.NoPungClaims }},
+	{"no-kong", func(h *HouseRules) *bool { return &h.NoKongClaims }},
+	{"no-concealed-kong", func(h *HouseRules) *bool { return &h.NoConcealedKongs }},
+	{"no-bonus-tiles", func(h *HouseRules) *bool { return &h.NoBonusTiles }},
+	{"no-interrupts", func(h *HouseRules) *bool { return &h.NoClaimInterrupts }},
+}
+
+// Switch on the house rule with the given name.
+func (h *HouseRules) Enable(name string) error {
+	for _, r := range houseRuleNames {
+		if r.name == name {
+			*r.toggle(h) = true
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown house rule [%s]", name)
+}
+
+// The names of the house rules that are switched on.
+func (h HouseRules) Names() []string {
+	names := make([]string, 0)
+	for _, r := range houseRuleNames {
+		if *r.toggle(&h) {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+// Whether players can make the claim under this ruleset.
+func (r Ruleset) allowsClaim(claim Claim) bool {
+	switch {
+	case claim == ClaimChow && r.House.NoChowClaims,
+		claim == ClaimPung && r.House.NoPungClaims,
+		claim == ClaimKong && r.House.NoKongClaims:
+		return false
+	}
+	for _, c := range r.Claims {
+		if c == claim {
+			return true
+		}
+	}
+	return false
+}
+
+// Priority of an action in response to a discarded tile, higher priorities win.
+// Passing has the lowest priority, actions that are no claims have priority zero.
+// Only mahjong can be claimed by several players at once, which is resolved by the multiple win policy.
+func (r Ruleset) claimPriority(action state.Action) int {
+	var claim Claim
+	switch action.(type) {
+	case DoNothing:
+		return 1
+	case DeclareChow:
+		claim = ClaimChow
+	case DeclarePung:
+		claim = ClaimPung
+	case DeclareKong:
+		claim = ClaimKong
+	case DeclareMahjong:
+		claim = ClaimMahjong
+	default:
+		return 0
+	}
+
+	for i, c := range r.Claims {
+		if c == claim {
+			return len(r.Claims) - i + 1
+		}
+	}
+	return 0
+}
+
+// SpecialHands are the hands that do not consist of four sets and a pair.
+type SpecialHands struct {
+	SevenPairs bool
+	// Whether the seven pairs have to be different, so four of a kind does not count as two pairs.
+	DistinctPairs   bool
+	ThirteenOrphans bool
+	// Whether nine gates is scored as a limit hand. Nine gates is a regular hand, so this only affects the scorer.
+	NineGates bool
+	// Whether hands with a knitted straight, and honors and knitted tiles, count.
+	Knitted bool
+}
+
+// Whether the ruleset accepts the decomposition as a winning hand.
+func (s SpecialHands) allows(d Decomposition) bool {
+	switch d.Form {
+	case FormSevenPairs:
+		if !s.SevenPairs {
+			return false
+		}
+		if s.DistinctPairs {
+			for i := 1; i < len(d.Pairs); i++ {
+				if d.Pairs[i] == d.Pairs[i-1] {
+					return false
+				}
+			}
+		}
+		return true
+	case FormThirteenOrphans:
+		return s.ThirteenOrphans
+	case FormKnittedStraight, FormHonorsAndKnitted:
+		return s.Knitted
+	default:
+		return true
+	}
+}
+
+// The decompositions of the hand that the ruleset accepts. Hands matched against a card are not decomposed.
+func (r Ruleset) decompose(concealed *TileCollection, exposed *CombinationCollection) []Decomposition {
+	decompositions := make([]Decomposition, 0)
+	if r.Card != nil {
+		return decompositions
+	}
+	for _, d := range Decompose(concealed, exposed) {
+		if r.SpecialHands.allows(d) {
+			decompositions = append(decompositions, d)
+		}
+	}
+	return decompositions
+}
+
+// Whether the concealed tiles together with the given tile complete the hand under this ruleset.
+func (r Ruleset) isWinningHand(concealed *TileCollection, exposed *CombinationCollection, tile Tile) bool {
+	if r.Card != nil {
+		hand := concealed.copy()
+		hand.add(tile)
+		return len(r.Card.matches(hand, exposed)) > 0
+	}
+
+	counts := countsOf(concealed)
+	counts[tile]++
+
+	if counts.isComplete() {
+		return true
+	}
+
+	if r.SpecialHands.SevenPairs && counts.isSevenPairs() {
+		if !r.SpecialHands.DistinctPairs {
+			return true
+		}
+		for _, count := range counts {
+			if count == 4 {
+				return false
+			}
+		}
+		return true
+	}
+
+	if r.SpecialHands.ThirteenOrphans && counts.isThirteenOrphans() {
+		return true
+	}
+
+	return r.SpecialHands.Knitted && (counts.isKnittedStraight() || counts.isHonorsAndKnitted())
+}
+
+// All tiles that would complete the hand formed by the concealed tiles and exposed sets under this ruleset.
+func (r Ruleset) WaitingTiles(concealed *TileCollection, exposed *CombinationCollection) []Tile {
+	waits := make([]Tile, 0)
+	for _, tile := range r.tileKinds() {
+		if r.isWinningHand(concealed, exposed, tile) {
+			waits = append(waits, tile)
+		}
+	}
+	return waits
+}
+
+// The kinds of tiles that can be part of a hand under this ruleset: those in the set the wall is built from,
+// except the tiles that are set aside as bonus tiles.
+func (r Ruleset) tileKinds() []Tile {
+	tiles := r.tileSet()
+	set := tiles.collection()
+
+	candidates := append(allTileKinds(), FlowerPlumb, FlowerOrchid, FlowerChrysanthemum, FlowerBamboo, SeasonSpring, SeasonSummer, SeasonAutumn, SeasonWinter, Joker)
+	kinds := make([]Tile, 0, len(candidates))
+	for _, tile := range candidates {
+		if set.NumOf(tile) > 0 && !tiles.isBonusTile(tile) {
+			kinds = append(kinds, tile)
+		}
+	}
+	return kinds
+}
+
+// MatchLength is how many hands are played before the game ends.
+type MatchLength int
+
+const (
+	// A single hand, regardless of who wins it.
+	MatchSingleHand MatchLength = iota
+	// The East round, in which every player is dealer at least once.
+	MatchEastOnly
+	// The East and South rounds.
+	MatchEastSouth
+	// All four prevalent winds.
+	MatchFull
+)
+
+// Number of prevalent winds that are played, starting from East. Zero for a single hand.
+func (l MatchLength) prevalentWinds() int {
+	switch l {
+	case MatchEastOnly:
+		return 1
+	case MatchEastSouth:
+		return 2
+	case MatchFull:
+		return 4
+	default:
+		return 0
+	}
+}
+
+var matchLengths = map[string]MatchLength{
+	"hand":       MatchSingleHand,
+	"east":       MatchEastOnly,
+	"east-south": MatchEastSouth,
+	"full":       MatchFull,
+}
+
+// The match length with the given name, which is one of hand, east, east-south and full.
+func MatchLengthByName(name string) (MatchLength, error) {
+	length, has := matchLengths[name]
+	if !has {
+		return 0, fmt.Errorf("unknown match length [%s]", name)
+	}
+	return length, nil
+}
+
+type MultipleWinPolicy int
+
+const (
+	// Only the first claiming player in turn order after the discarder wins, also known as head bump.
+	MultipleWinsHeadBump MultipleWinPolicy = iota
+	// Every claiming player wins and is paid.
+	MultipleWinsAllowed
+	// Two claiming players both win, but when three players claim the round ends in an abortive draw.
+	MultipleWinsAbortOnTriple
+)
+
+type DealerRetention int
+
+const (
+	// The dealer seat passes on after every hand.
+	DealerAlwaysRotates DealerRetention = iota
+	// The dealer keeps the seat after winning.
+	DealerKeepsOnWin
+	// The dealer keeps the seat after winning, or after being tenpai when the wall runs out.
+	DealerKeepsOnWinOrTenpai
+	// The dealer keeps the seat after winning, or whenever the wall runs out.
+	DealerKeepsOnWinOrDraw
+)
+
+// The classical Chinese game, with flowers and seasons and scoring in points and doubles.
+func ClassicalRuleset() Ruleset {
+	specialHands := SpecialHands{
+		SevenPairs:      false,
+		ThirteenOrphans: true,
+		NineGates:       true,
+	}
+
+	return Ruleset{
+		Name:                 "classical",
+		Players:              4,
+		Tiles:                TileSet{Honors: true, BonusTiles: true},
+		HandSize:             13,
+		Claims:               []Claim{ClaimMahjong, ClaimKong, ClaimPung, ClaimChow},
+		Length:               MatchFull,
+		Scorer:               ClassicalScorer{Limit: 1000, NineGates: specialHands.NineGates},
+		RevealConcealedKongs: false,
+		DealerRetention:      DealerKeepsOnWin,
+		MultipleWins:         MultipleWinsHeadBump,
+		Furiten:              false,
+		SpecialHands:         specialHands,
+	}
+}
+
+// Japanese riichi mahjong, without flowers, with one red five per suit and scoring in han and fu.
+func RiichiRuleset() Ruleset {
+	specialHands := SpecialHands{
+		SevenPairs:      true,
+		DistinctPairs:   true,
+		ThirteenOrphans: true,
+		NineGates:       true,
+	}
+
+	return Ruleset{
+		Name:                 "riichi",
+		Players:              4,
+		Tiles:                TileSet{Honors: true, BonusTiles: false, RedFives: 1},
+		HandSize:             13,
+		Claims:               []Claim{ClaimMahjong, ClaimKong, ClaimPung, ClaimChow},
+		Length:               MatchEastSouth,
+		BustEnds:             true,
+		OvertimeTarget:       30000,
+		StartingScore:        25000,
+		Scorer:               RiichiScorer{},
+		RevealConcealedKongs: true,
+		DealerRetention:      DealerKeepsOnWinOrTenpai,
+		MultipleWins:         MultipleWinsAbortOnTriple,
+		Furiten:              true,
+		SpecialHands:         specialHands,
+		Riichi:               true,
+		RiichiDeposit:        1000,
+		DoraIndicators:       5,
+	}
+}
+
+// The Chinese Official rules used in competitions, with flowers, 81 fan and a minimum of eight points to win.
+func MCRRuleset() Ruleset {
+	specialHands := SpecialHands{
+		SevenPairs:      true,
+		ThirteenOrphans: true,
+		NineGates:       true,
+		Knitted:         true,
+	}
+
+	return Ruleset{
+		Name:                 "mcr",
+		Players:              4,
+		Tiles:                TileSet{Honors: true, BonusTiles: true},
+		HandSize:             13,
+		Claims:               []Claim{ClaimMahjong, ClaimKong, ClaimPung, ClaimChow},
+		Length:               MatchFull,
+		Scorer:               MCRScorer{MinimumPoints: 8, BasePayment: 8},
+		RevealConcealedKongs: false,
+		DealerRetention:      DealerAlwaysRotates,
+		MultipleWins:         MultipleWinsHeadBump,
+		Furiten:              false,
+		SpecialHands:         specialHands,
+	}
+}
+
+// Old style Hong Kong mahjong, with flowers and seasons, scoring in faan and a minimum of three faan to win.
+func HongKongRuleset() Ruleset {
+	specialHands := SpecialHands{
+		SevenPairs:      false,
+		ThirteenOrphans: true,
+		NineGates:       true,
+	}
+
+	return Ruleset{
+		Name:                 "hongkong",
+		Players:              4,
+		Tiles:                TileSet{Honors: true, BonusTiles: true},
+		HandSize:             13,
+		Claims:               []Claim{ClaimMahjong, ClaimKong, ClaimPung, ClaimChow},
+		Length:               MatchFull,
+		Scorer:               HongKongScorer{MinimumFaan: 3, LimitFaan: 10},
+		RevealConcealedKongs: false,
+		DealerRetention:      DealerKeepsOnWin,
+		MultipleWins:         MultipleWinsHeadBump,
+		Furiten:              false,
+		SpecialHands:         specialHands,
+	}
+}
+
+// Taiwanese mahjong, with hands of sixteen tiles that need five sets and a pair, flowers and seasons and scoring in tai.
+func TaiwaneseRuleset() Ruleset {
+	return Ruleset{
+		Name:                 "taiwanese",
+		Players:              4,
+		Tiles:                TileSet{Honors: true, BonusTiles: true},
+		HandSize:             16,
+		Claims:               []Claim{ClaimMahjong, ClaimKong, ClaimPung, ClaimChow},
+		Length:               MatchFull,
+		Scorer:               TaiwaneseScorer{Base: 300, PerTai: 100},
+		RevealConcealedKongs: false,
+		DealerRetention:      DealerKeepsOnWin,
+		MultipleWins:         MultipleWinsHeadBump,
+		Furiten:              false,
+		SpecialHands:         SpecialHands{},
+	}
+}
+
+// Three player riichi mahjong, without chows and the two to eight of characters. North winds are set aside as bonus tiles that count as dora.
+func SanmaRuleset() Ruleset {
+	rules := RiichiRuleset()
+	rules.Name = "sanma"
+	rules.Players = 3
+	rules.Tiles.Excluded = []Tile{Characters2, Characters3, Characters4, Characters5, Characters6, Characters7, Characters8}
+	rules.Tiles.BonusHonors = []Tile{NorthWind}
+	rules.Claims = []Claim{ClaimMahjong, ClaimKong, ClaimPung}
+	rules.StartingScore = 35000
+	rules.OvertimeTarget = 40000
+	return rules
+}
+
+// Sichuan bloody battle, with only the three suits and no chows. Every player declares a suit to get rid of,
+// and after a win the others play on until one of them is left or the wall is exhausted.
+func SichuanRuleset() Ruleset {
+	return Ruleset{
+		Name:                 "sichuan",
+		Players:              4,
+		Tiles:                TileSet{Honors: false, BonusTiles: false},
+		HandSize:             13,
+		Claims:               []Claim{ClaimMahjong, ClaimKong, ClaimPung},
+		Length:               MatchFull,
+		Scorer:               SichuanScorer{Base: 1, LimitFan: 4},
+		RevealConcealedKongs: false,
+		DealerRetention:      DealerAlwaysRotates,
+		MultipleWins:         MultipleWinsAllowed,
+		Furiten:              false,
+		SpecialHands:         SpecialHands{SevenPairs: true},
+
+		customizeStates: sichuanStates,
+	}
+}
+
+// American mahjong, with flowers and jokers in the hand and a Charleston before every hand. Hands have to match the card to win.
+// The card is published each year, so the ruleset is registered once a card is loaded, see RegisterRuleset.
+func AmericanRuleset(card *Card) Ruleset {
+	return Ruleset{
+		Name:                 "american",
+		Players:              4,
+		Tiles:                TileSet{Honors: true, BonusTiles: true, BonusTilesInHand: true, Jokers: 8},
+		HandSize:             13,
+		Claims:               []Claim{ClaimMahjong, ClaimKong, ClaimPung},
+		Length:               MatchEastOnly,
+		Scorer:               AmericanScorer{Card: card},
+		RevealConcealedKongs: false,
+		DealerRetention:      DealerAlwaysRotates,
+		MultipleWins:         MultipleWinsHeadBump,
+		Furiten:              false,
+		SpecialHands:         SpecialHands{},
+		Card:                 card,
+
+		customizeStates: americanStates,
+	}
+}
+
+var rulesets = map[string]func() Ruleset{
+	"classical": ClassicalRuleset,
+	"hongkong":  HongKongRuleset,
+	"taiwanese": TaiwaneseRuleset,
+	"mcr":       MCRRuleset,
+	"riichi":    RiichiRuleset,
+	"sanma":     SanmaRuleset,
+	"sichuan":   SichuanRuleset,
+}
+
+// RegisterRuleset makes the ruleset available under the given name, replacing any ruleset registered under it before.
+// Should be called before games are started.
+func RegisterRuleset(name string, constructor func() Ruleset) {
+	rulesets[name] = constructor
+}
+
+// The ruleset registered under the given name.
+func RulesetByName(name string) (Ruleset, error) {
+	constructor, has := rulesets[name]
+	if !has {
+		return Ruleset{}, fmt.Errorf("unknown ruleset [%s]", name)
+	}
+	return constructor(), nil
+}
+
+// The names of all registered rulesets, in alphabetical order.
+func RulesetNames() []string {
+	names := make([]string, 0, len(rulesets))
+	for name := range rulesets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,71 @@
+package mahjong
+
+import "fmt"
+
+// AmericanScorer values hands by the card they are matched against. A hand is worth the value the card lists for it,
+// doubled if it was made without jokers while it could have used them.
+type AmericanScorer struct {
+	Card *Card
+}
+
+func (s AmericanScorer) Score(hand WinningHand, ctx WinContext) Score {
+	var best Score
+	for i, match := range s.Card.matches(hand.Concealed, hand.Exposed) {
+		score := s.scoreMatch(match, hand)
+		if i == 0 || score.Total > best.Total {
+			best = score
+		}
+	}
+	if best.Items == nil {
+		best.Items = []ScoreItem{}
+	}
+	return best
+}
+
+func (s AmericanScorer) scoreMatch(match CardHand, hand WinningHand) Score {
+	items := []ScoreItem{{Name: fmt.Sprintf("%s %s", match.Section, match.Pattern), Points: match.Value}}
+
+	if hand.Concealed.NumOf(Joker) == 0 && match.allowsJokers() {
+		items = append(items, ScoreItem{Name: "Jokerless", Doubles: 1})
+	}
+
+	total := match.Value
+	for _, item := range items {
+		total <<= uint(item.Doubles)
+	}
+
+	return Score{
+		Items: items,
+		Value: match.Value,
+		Total: total,
+	}
+}
+
+// Whether jokers could be used in the hand, which needs a group of three or more tiles.
+func (h CardHand) allowsJokers() bool {
+	for _, g := range h.groups {
+		if g.size >= 3 {
+			return true
+		}
+	}
+	return false
+}
+
+// The discarder pays double the hand value and the other players pay the hand value. When self drawn everyone pays double.
+func (s AmericanScorer) Payments(score Score, ctx WinContext) map[int]int {
+	payments := make(map[int]int, len(ctx.Seats))
+
+	for _, seat := range ctx.Seats {
+		if seat == ctx.Winner {
+			continue
+		}
+		amount := score.Total
+		if ctx.IsSelfDrawn() || seat == ctx.Discarder {
+			amount *= 2
+		}
+		payments[seat] -= amount
+		payments[ctx.Winner] += amount
+	}
+
+	return payments
+}
@@ -0,0 +1,286 @@
+package mahjong
+
+// ClassicalScorer values hands with the points and doubles of the classical Chinese game.
+// Each other player pays the winner the hand value, East pays and receives double.
+type ClassicalScorer struct {
+	// Maximum value of a hand, zero means no limit.
+	Limit int
+	// Whether nine gates is a limit hand.
+	NineGates bool
+}
+
+func (s ClassicalScorer) Score(hand WinningHand, ctx WinContext) Score {
+	if s.NineGates && IsNineGates(hand.Concealed, hand.Exposed) {
+		return s.limitHand("Nine gates")
+	}
+
+	return bestScore(hand.Decompositions, func(d Decomposition) Score {
+		if d.Form == FormThirteenOrphans {
+			return s.limitHand("Thirteen orphans")
+		}
+		var best Score
+		for i, wait := range d.Waits(ctx.WinningTile) {
+			score := s.scoreReading(d, wait, ctx)
+			if i == 0 || score.Total > best.Total {
+				best = score
+			}
+		}
+		return best
+	})
+}
+
+func (s ClassicalScorer) scoreReading(d Decomposition, wait Wait, ctx WinContext) Score {
+	items := []ScoreItem{{Name: "Mahjong", Points: 20}}
+
+	if ctx.IsSelfDrawn() {
+		items = append(items, ScoreItem{Name: "Self drawn", Points: 2})
+	}
+
+	if wait == WaitPair || wait == WaitEdge || wait == WaitClosed {
+		items = append(items, ScoreItem{Name: "Single wait", Points: 2})
+	}
+
+	if d.Form == FormSevenPairs {
+		items = append(items, ScoreItem{Name: "Seven pairs", Doubles: 2})
+	}
+
+	completedByDiscard := wait == WaitPung && !ctx.IsSelfDrawn()
+	for _, set := range d.Concealed {
+		if p, isPung := set.(Pung); isPung && completedByDiscard && p.Tile == ctx.WinningTile {
+			// a pung completed by a discard counts as exposed
+			items = append(items, s.setItems(set, false, ctx)...)
+			completedByDiscard = false
+			continue
+		}
+		items = append(items, s.setItems(set, true, ctx)...)
+	}
+
+	isConcealed := true
+	for _, set := range d.Exposed {
+		kong, isKong := set.(Kong)
+		concealed := isKong && kong.Concealed
+		if !concealed {
+			isConcealed = false
+		}
+		items = append(items, s.setItems(set, concealed, ctx)...)
+	}
+
+	for _, pair := range pairs(d) {
+		if pair.IsDragon() {
+			items = append(items, ScoreItem{Name: "Pair of dragons", Points: 2})
+		}
+		if pair == ctx.SeatWind.Tile() {
+			items = append(items, ScoreItem{Name: "Pair of seat wind", Points: 2})
+		}
+		if pair == ctx.PrevalentWind.Tile() {
+			items = append(items, ScoreItem{Name: "Pair of prevalent wind", Points: 2})
+		}
+	}
+
+	for _, bonus := range ctx.BonusTiles {
+		items = append(items, ScoreItem{Name: "Bonus tile", Points: 4})
+		if bonus == FlowerPlumb+Tile(ctx.SeatWind) || bonus == SeasonSpring+Tile(ctx.SeatWind) {
+			items = append(items, ScoreItem{Name: "Own flower or season", Doubles: 1})
+		}
+	}
+
+	if isConcealed {
+		items = append(items, ScoreItem{Name: "Concealed hand", Doubles: 1})
+	}
+
+	if ctx.ReplacementTile {
+		items = append(items, ScoreItem{Name: "Winning on a replacement tile", Doubles: 1})
+	}
+
+	if ctx.RobbingKong {
+		items = append(items, ScoreItem{Name: "Robbing the kong", Doubles: 1})
+	}
+
+	if ctx.LastTile {
+		items = append(items, ScoreItem{Name: "Winning on the last tile", Doubles: 1})
+	}
+
+	hasChow := false
+	for _, set := range d.Sets() {
+		if _, isChow := set.(Chow); isChow {
+			hasChow = true
+		}
+	}
+	if !hasChow && d.Form == FormRegular {
+		items = append(items, ScoreItem{Name: "All pungs", Doubles: 1})
+	}
+
+	switch handSuits(d) {
+	case suitsAllHonors:
+		items = append(items, ScoreItem{Name: "All honors", Doubles: 3})
+	case suitsPure:
+		items = append(items, ScoreItem{Name: "Full flush", Doubles: 3})
+	case suitsMixed:
+		items = append(items, ScoreItem{Name: "Half flush", Doubles: 1})
+	}
+
+	points := 0
+	doubles := 0
+	for _, item := range items {
+		points += item.Points
+		doubles += item.Doubles
+	}
+
+	total := points
+	for i := 0; i < doubles; i++ {
+		total *= 2
+		if s.Limit > 0 && total >= s.Limit {
+			total = s.Limit
+			break
+		}
+	}
+
+	return Score{
+		Items: items,
+		Value: total,
+		Total: total,
+	}
+}
+
+// A hand that is always worth the limit, regardless of how it was won.
+func (s ClassicalScorer) limitHand(name string) Score {
+	limit := s.Limit
+	if limit == 0 {
+		limit = 1000
+	}
+	return Score{
+		Items: []ScoreItem{{Name: name, Points: limit}},
+		Value: limit,
+		Total: limit,
+	}
+}
+
+func (s ClassicalScorer) setItems(set Combination, concealed bool, ctx WinContext) []ScoreItem {
+	var tile Tile
+	var name string
+	var points int
+
+	switch c := set.(type) {
+	case Pung:
+		tile = c.Tile
+		name = "pung"
+		points = 2
+	case Kong:
+		tile = c.Tile
+		name = "kong"
+		points = 8
+	default:
+		return nil
+	}
+
+	if concealed {
+		name = "Concealed " + name
+		points *= 2
+	} else {
+		name = "Exposed " + name
+	}
+
+	if tile.IsTerminal() || tile.IsHonor() {
+		name += " of terminals or honors"
+		points *= 2
+	} else {
+		name += " of simples"
+	}
+
+	items := []ScoreItem{{Name: name, Points: points}}
+
+	if tile.IsDragon() {
+		items = append(items, ScoreItem{Name: "Pung of dragons", Doubles: 1})
+	}
+	if tile == ctx.SeatWind.Tile() {
+		items = append(items, ScoreItem{Name: "Pung of seat wind", Doubles: 1})
+	}
+	if tile == ctx.PrevalentWind.Tile() {
+		items = append(items, ScoreItem{Name: "Pung of prevalent wind", Doubles: 1})
+	}
+
+	return items
+}
+
+func (s ClassicalScorer) Payments(score Score, ctx WinContext) map[int]int {
+	payments := make(map[int]int, len(ctx.Seats))
+
+	for _, seat := range ctx.Seats {
+		if seat == ctx.Winner {
+			continue
+		}
+		amount := score.Total
+		if seat == ctx.Dealer || ctx.Winner == ctx.Dealer {
+			amount *= 2
+		}
+		payments[seat] -= amount
+		payments[ctx.Winner] += amount
+	}
+
+	return payments
+}
+
+type suitComposition int
+
+const (
+	suitsMultiple suitComposition = iota
+	suitsMixed
+	suitsPure
+	suitsAllHonors
+)
+
+// Whether the tiles in the decomposition come from a single suit, with or without honors.
+func handSuits(d Decomposition) suitComposition {
+	tiles := pairs(d)
+	for _, set := range d.Sets() {
+		tiles = append(tiles, combinationTile(set))
+	}
+
+	suit := Tile(-1)
+	hasHonors := false
+	for _, tile := range tiles {
+		if tile.IsHonor() {
+			hasHonors = true
+			continue
+		}
+		if suit != -1 && tile/10 != suit {
+			return suitsMultiple
+		}
+		suit = tile / 10
+	}
+
+	switch {
+	case suit == -1:
+		return suitsAllHonors
+	case hasHonors:
+		return suitsMixed
+	default:
+		return suitsPure
+	}
+}
+
+// The pairs in the decomposition, which are seven for seven pairs, none for honors and knitted tiles and one for the other forms.
+func pairs(d Decomposition) []Tile {
+	switch d.Form {
+	case FormSevenPairs:
+		return d.Pairs
+	case FormHonorsAndKnitted:
+		return nil
+	}
+	return []Tile{d.Pair}
+}
+
+// The tile a combination is made of, or the first tile in case of a chow.
+func combinationTile(c Combination) Tile {
+	switch comb := c.(type) {
+	case Chow:
+		return comb.FirstTile
+	case Pung:
+		return comb.Tile
+	case Kong:
+		return comb.Tile
+	case BonusTile:
+		return comb.Tile
+	}
+	return 0
+}
@@ -0,0 +1,251 @@
+package mahjong
+
+// HongKongScorer values hands with the faan of old style Hong Kong mahjong.
+// The faan of a hand are looked up in a points table, limit hands are always worth the maximum.
+type HongKongScorer struct {
+	// Faan a hand needs to be declared as a win.
+	MinimumFaan int
+	// Maximum number of faan, which limit hands are worth.
+	LimitFaan int
+}
+
+// Base points per number of faan.
+var hongKongPoints = []int{1, 2, 4, 8, 16, 24, 32, 48, 64, 96, 128, 192, 256, 384}
+
+func (s HongKongScorer) Score(hand WinningHand, ctx WinContext) Score {
+	if IsNineGates(hand.Concealed, hand.Exposed) {
+		return s.limitHand("Nine gates")
+	}
+	if len(ctx.BonusTiles) == 8 {
+		return s.limitHand("All flowers and seasons")
+	}
+	if ctx.FirstDraw && ctx.Winner == ctx.Dealer {
+		return s.limitHand("Heavenly hand")
+	}
+	if ctx.FirstDraw {
+		return s.limitHand("Earthly hand")
+	}
+
+	return bestScore(hand.Decompositions, func(d Decomposition) Score {
+		if d.Form == FormThirteenOrphans {
+			return s.limitHand("Thirteen orphans")
+		}
+		var best Score
+		for i, wait := range d.Waits(ctx.WinningTile) {
+			score := s.scoreReading(d, wait, ctx)
+			if i == 0 || score.Total > best.Total {
+				best = score
+			}
+		}
+		return best
+	})
+}
+
+// A hand needs the minimum number of faan.
+func (s HongKongScorer) AllowsWin(hand WinningHand, ctx WinContext) bool {
+	return s.Score(hand, ctx).Value >= s.MinimumFaan
+}
+
+func (s HongKongScorer) scoreReading(d Decomposition, wait Wait, ctx WinContext) Score {
+	var pungs []Tile
+	chows := 0
+	kongs := 0
+	concealedPungs := 0
+	isConcealed := true
+
+	completedByDiscard := wait == WaitPung && !ctx.IsSelfDrawn()
+	for _, set := range d.Concealed {
+		switch c := set.(type) {
+		case Chow:
+			chows++
+		case Pung:
+			pungs = append(pungs, c.Tile)
+			if completedByDiscard && c.Tile == ctx.WinningTile {
+				// a pung completed by a discard counts as exposed
+				completedByDiscard = false
+				continue
+			}
+			concealedPungs++
+		}
+	}
+	for _, set := range d.Exposed {
+		switch c := set.(type) {
+		case Chow:
+			chows++
+			isConcealed = false
+		case Pung:
+			pungs = append(pungs, c.Tile)
+			isConcealed = false
+		case Kong:
+			pungs = append(pungs, c.Tile)
+			kongs++
+			if c.Concealed {
+				concealedPungs++
+			} else {
+				isConcealed = false
+			}
+		}
+	}
+
+	tiles := pairs(d)
+	for _, set := range d.Sets() {
+		tiles = append(tiles, combinationTile(set))
+		if c, isChow := set.(Chow); isChow {
+			tiles = append(tiles, c.FirstTile+2)
+		}
+	}
+	allTiles := func(is func(Tile) bool) bool {
+		for _, tile := range tiles {
+			if !is(tile) {
+				return false
+			}
+		}
+		return true
+	}
+
+	dragons := 0
+	winds := 0
+	for _, pung := range pungs {
+		if pung.IsDragon() {
+			dragons++
+		}
+		if pung.IsWind() {
+			winds++
+		}
+	}
+
+	switch {
+	case d.Form == FormRegular && winds == 4:
+		return s.limitHand("Big four winds")
+	case allTiles(Tile.IsHonor):
+		return s.limitHand("All honors")
+	case allTiles(Tile.IsTerminal):
+		return s.limitHand("All terminals")
+	case concealedPungs == 4:
+		return s.limitHand("Four concealed pungs")
+	case kongs == 4:
+		return s.limitHand("Four kongs")
+	}
+
+	items := make([]ScoreItem, 0)
+	add := func(name string, faan int) {
+		items = append(items, ScoreItem{Name: name, Doubles: faan})
+	}
+
+	if ctx.IsSelfDrawn() {
+		add("Self drawn", 1)
+	}
+	if isConcealed {
+		add("Concealed hand", 1)
+	}
+	if d.Form == FormRegular && chows == 4 {
+		add("All chows", 1)
+	}
+	if d.Form == FormRegular && chows == 0 {
+		add("All pungs", 3)
+	}
+
+	switch handSuits(d) {
+	case suitsPure:
+		add("Full flush", 7)
+	case suitsMixed:
+		add("Half flush", 3)
+	}
+	if allTiles(Tile.IsTerminalOrHonor) {
+		add("Terminals and honors", 1)
+	}
+
+	pairIsDragon := d.Form == FormRegular && d.Pair.IsDragon()
+	switch {
+	case dragons == 3:
+		add("Big three dragons", 8)
+	case dragons == 2 && pairIsDragon:
+		add("Little three dragons", 5)
+	default:
+		for i := 0; i < dragons; i++ {
+			add("Pung of dragons", 1)
+		}
+	}
+
+	if winds == 3 && d.Form == FormRegular && d.Pair.IsWind() {
+		add("Little four winds", 6)
+	}
+	for _, pung := range pungs {
+		if pung == ctx.SeatWind.Tile() {
+			add("Pung of seat wind", 1)
+		}
+		if pung == ctx.PrevalentWind.Tile() {
+			add("Pung of prevalent wind", 1)
+		}
+	}
+
+	if ctx.ReplacementTile {
+		add("Winning on a replacement tile", 1)
+	}
+	if ctx.RobbingKong {
+		add("Robbing the kong", 1)
+	}
+	if ctx.LastTile {
+		add("Winning on the last tile", 1)
+	}
+
+	items = append(items, s.bonusTileItems(ctx)...)
+
+	faan := 0
+	for _, item := range items {
+		faan += item.Doubles
+	}
+	if faan > s.LimitFaan {
+		faan = s.LimitFaan
+	}
+
+	return Score{
+		Items: items,
+		Value: faan,
+		Total: s.points(faan),
+	}
+}
+
+// Faan for flowers and seasons, or a single faan for having none.
+func (s HongKongScorer) bonusTileItems(ctx WinContext) []ScoreItem {
+	if len(ctx.BonusTiles) == 0 {
+		return []ScoreItem{{Name: "No flowers or seasons", Doubles: 1}}
+	}
+	return seatBonusTileItems(ctx)
+}
+
+// A hand that is always worth the limit, regardless of how it was won.
+func (s HongKongScorer) limitHand(name string) Score {
+	return Score{
+		Items: []ScoreItem{{Name: name, Doubles: s.LimitFaan}},
+		Value: s.LimitFaan,
+		Total: s.points(s.LimitFaan),
+	}
+}
+
+// The base points for the number of faan.
+func (s HongKongScorer) points(faan int) int {
+	if faan >= len(hongKongPoints) {
+		return hongKongPoints[len(hongKongPoints)-1]
+	}
+	return hongKongPoints[faan]
+}
+
+// The discarder pays double the base points and the other players pay the base points. When self drawn everyone pays double.
+func (s HongKongScorer) Payments(score Score, ctx WinContext) map[int]int {
+	payments := make(map[int]int, len(ctx.Seats))
+
+	for _, seat := range ctx.Seats {
+		if seat == ctx.Winner {
+			continue
+		}
+		amount := score.Total
+		if ctx.IsSelfDrawn() || seat == ctx.Discarder {
+			amount *= 2
+		}
+		payments[seat] -= amount
+		payments[ctx.Winner] += amount
+	}
+
+	return payments
+}
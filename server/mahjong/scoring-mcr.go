@@ -0,0 +1,828 @@
+package mahjong
+
+import "sort"
+
+// MCRScorer values hands with the 81 fan of the Chinese Official rules, also known as the Mahjong Competition Rules.
+// Fan that are implied by another fan of the hand are not counted. A hand needs a minimum number of points to win, not counting flowers.
+type MCRScorer struct {
+	// Points a hand needs to be declared as a win, not counting flowers.
+	MinimumPoints int
+	// Amount every other player pays the winner on top of the hand value.
+	BasePayment int
+}
+
+// mcrFan is a scoring pattern of the Chinese Official rules.
+type mcrFan struct {
+	points int
+	// Fan that are implied by this fan and are not counted along with it.
+	excludes []string
+}
+
+var mcrFans = map[string]mcrFan{
+	// 88 points
+	"Big four winds":      {88, []string{"Big three winds", "Little four winds", "All pungs", "Seat wind", "Prevalent wind", "Pung of terminals or honors"}},
+	"Big three dragons":   {88, []string{"Little three dragons", "Two dragon pungs", "Dragon pung"}},
+	"All green":           {88, []string{"Half flush"}},
+	"Nine gates":          {88, []string{"Full flush", "Concealed hand", "Pung of terminals or honors", "No honors", "One voided suit"}},
+	"Four kongs":          {88, []string{"Three kongs", "Two melded kongs", "Two concealed kongs", "Melded kong", "Concealed kong", "All pungs", "Single wait"}},
+	"Seven shifted pairs": {88, []string{"Seven pairs", "Full flush", "Concealed hand", "Single wait", "No honors", "One voided suit"}},
+	"Thirteen orphans":    {88, []string{"All types", "Concealed hand", "Single wait"}},
+
+	// 64 points
+	"All terminals":        {64, []string{"All terminals and honors", "All pungs", "Outside hand", "Pung of terminals or honors", "No honors"}},
+	"Little four winds":    {64, []string{"Big three winds"}},
+	"Little three dragons": {64, []string{"Two dragon pungs", "Dragon pung"}},
+	"All honors":           {64, []string{"All terminals and honors", "All pungs", "Outside hand", "Pung of terminals or honors", "One voided suit"}},
+	"Four concealed pungs": {64, []string{"Three concealed pungs", "Two concealed pungs", "All pungs", "Fully concealed hand", "Concealed hand"}},
+	"Pure terminal chows":  {64, []string{"Seven pairs", "Full flush", "All chows", "Pure double chow", "Two terminal chows", "No honors", "One voided suit"}},
+
+	// 48 points
+	"Quadruple chow":          {48, []string{"Pure triple chow", "Pure double chow", "Tile hog"}},
+	"Four pure shifted pungs": {48, []string{"Pure shifted pungs", "Pure triple chow", "All pungs"}},
+
+	// 32 points
+	"Four pure shifted chows":  {32, []string{"Pure shifted chows", "Short straight", "Two terminal chows"}},
+	"Three kongs":              {32, []string{"Two melded kongs", "Two concealed kongs", "Melded kong", "Concealed kong"}},
+	"All terminals and honors": {32, []string{"All pungs", "Outside hand", "Pung of terminals or honors"}},
+
+	// 24 points
+	"Seven pairs":                      {24, []string{"Concealed hand", "Single wait"}},
+	"Greater honors and knitted tiles": {24, []string{"Lesser honors and knitted tiles", "All types", "Concealed hand", "Single wait"}},
+	"All even pungs":                   {24, []string{"All pungs", "All simples", "No honors"}},
+	"Full flush":                       {24, []string{"No honors", "One voided suit"}},
+	"Pure triple chow":                 {24, []string{"Pure double chow"}},
+	"Pure shifted pungs":               {24, nil},
+	"Upper tiles":                      {24, []string{"Upper four", "No honors"}},
+	"Middle tiles":                     {24, []string{"All simples", "No honors"}},
+	"Lower tiles":                      {24, []string{"Lower four", "No honors"}},
+
+	// 16 points
+	"Pure straight":               {16, nil},
+	"Three-suited terminal chows": {16, []string{"All chows", "Two terminal chows", "Mixed double chow", "No honors"}},
+	"Pure shifted chows":          {16, nil},
+	"All fives":                   {16, []string{"All simples", "No honors"}},
+	"Triple pung":                 {16, nil},
+	"Three concealed pungs":       {16, []string{"Two concealed pungs"}},
+
+	// 12 points
+	"Lesser honors and knitted tiles": {12, []string{"All types", "Concealed hand", "Single wait"}},
+	"Knitted straight":                {12, nil},
+	"Upper four":                      {12, []string{"No honors"}},
+	"Lower four":                      {12, []string{"No honors"}},
+	"Big three winds":                 {12, nil},
+
+	// 8 points
+	"Mixed straight":            {8, nil},
+	"Reversible tiles":          {8, []string{"One voided suit"}},
+	"Mixed triple chow":         {8, nil},
+	"Mixed shifted pungs":       {8, nil},
+	"Chicken hand":              {8, nil},
+	"Last tile draw":            {8, []string{"Self drawn"}},
+	"Last tile claim":           {8, nil},
+	"Out with replacement tile": {8, []string{"Self drawn"}},
+	"Robbing the kong":          {8, []string{"Last tile"}},
+	"Two concealed kongs":       {8, []string{"Concealed kong", "Two concealed pungs"}},
+
+	// 6 points
+	"All pungs":           {6, nil},
+	"Half flush":          {6, []string{"One voided suit"}},
+	"Mixed shifted chows": {6, nil},
+	"All types":           {6, nil},
+	"Melded hand":         {6, []string{"Single wait"}},
+	"Two dragon pungs":    {6, []string{"Dragon pung"}},
+
+	// 4 points
+	"Outside hand":         {4, nil},
+	"Fully concealed hand": {4, []string{"Concealed hand", "Self drawn"}},
+	"Two melded kongs":     {4, []string{"Melded kong"}},
+	"Last tile":            {4, nil},
+
+	// 2 points
+	"Dragon pung":         {2, nil},
+	"Prevalent wind":      {2, nil},
+	"Seat wind":           {2, nil},
+	"Concealed hand":      {2, nil},
+	"All chows":           {2, []string{"No honors"}},
+	"Tile hog":            {2, nil},
+	"Double pung":         {2, nil},
+	"Two concealed pungs": {2, nil},
+	"Concealed kong":      {2, nil},
+	"All simples":         {2, []string{"No honors"}},
+
+	// 1 point
+	"Pure double chow":            {1, nil},
+	"Mixed double chow":           {1, nil},
+	"Short straight":              {1, nil},
+	"Two terminal chows":          {1, nil},
+	"Pung of terminals or honors": {1, nil},
+	"Melded kong":                 {1, nil},
+	"One voided suit":             {1, nil},
+	"No honors":                   {1, nil},
+	"Edge wait":                   {1, nil},
+	"Closed wait":                 {1, nil},
+	"Single wait":                 {1, nil},
+	"Self drawn":                  {1, nil},
+	"Flower tiles":                {1, nil},
+}
+
+func (s MCRScorer) Score(hand WinningHand, ctx WinContext) Score {
+	return bestScore(hand.Decompositions, func(d Decomposition) Score {
+		var best Score
+		for i, wait := range d.Waits(ctx.WinningTile) {
+			score := s.scoreReading(hand, d, wait, ctx)
+			if i == 0 || score.Total > best.Total {
+				best = score
+			}
+		}
+		return best
+	})
+}
+
+// A hand needs the minimum number of points, flowers do not count towards it.
+func (s MCRScorer) AllowsWin(hand WinningHand, ctx WinContext) bool {
+	return s.Score(hand, ctx).Value >= s.MinimumPoints
+}
+
+// The reading of a decomposition, split into the facts the fan are based on.
+type mcrReading struct {
+	hand WinningHand
+	d    Decomposition
+	wait Wait
+	ctx  WinContext
+	// Whether no tiles were claimed. Concealed kongs keep the hand concealed.
+	concealed bool
+	// Every tile of the hand, with kongs counted as four tiles.
+	tiles []Tile
+	// The first tile of each chow.
+	chows []Tile
+	// The tile of each pung, including kongs.
+	pungs          []Tile
+	meldedKongs    int
+	concealedKongs int
+	// Pungs and kongs that were formed without claiming a tile.
+	concealedPungs int
+
+	fans []string
+}
+
+func newMCRReading(hand WinningHand, d Decomposition, wait Wait, ctx WinContext) *mcrReading {
+	r := &mcrReading{
+		hand:      hand,
+		d:         d,
+		wait:      wait,
+		ctx:       ctx,
+		concealed: true,
+		tiles:     hand.Concealed.list(),
+	}
+
+	completedByDiscard := wait == WaitPung && !ctx.IsSelfDrawn()
+	for _, set := range d.Concealed {
+		switch c := set.(type) {
+		case Chow:
+			r.chows = append(r.chows, c.FirstTile)
+		case Pung:
+			r.pungs = append(r.pungs, c.Tile)
+			if completedByDiscard && c.Tile == ctx.WinningTile {
+				// a pung completed by a discard counts as exposed
+				completedByDiscard = false
+				continue
+			}
+			r.concealedPungs++
+		}
+	}
+
+	for _, set := range d.Exposed {
+		switch c := set.(type) {
+		case Chow:
+			r.chows = append(r.chows, c.FirstTile)
+			r.concealed = false
+			r.tiles = append(r.tiles, c.FirstTile, c.FirstTile+1, c.FirstTile+2)
+		case Pung:
+			r.pungs = append(r.pungs, c.Tile)
+			r.concealed = false
+			r.tiles = append(r.tiles, c.Tile, c.Tile, c.Tile)
+		case Kong:
+			r.pungs = append(r.pungs, c.Tile)
+			if c.Concealed {
+				r.concealedKongs++
+				r.concealedPungs++
+			} else {
+				r.meldedKongs++
+				r.concealed = false
+			}
+			r.tiles = append(r.tiles, c.Tile, c.Tile, c.Tile, c.Tile)
+		}
+	}
+
+	return r
+}
+
+func (s MCRScorer) scoreReading(hand WinningHand, d Decomposition, wait Wait, ctx WinContext) Score {
+	r := newMCRReading(hand, d, wait, ctx)
+	r.formFans()
+	r.honorFans()
+	r.setFans()
+	r.tileFans()
+	r.winFans()
+
+	items := r.items()
+	if len(items) == 0 {
+		items = append(items, ScoreItem{Name: "Chicken hand", Points: mcrFans["Chicken hand"].points})
+	}
+
+	value := 0
+	for _, item := range items {
+		value += item.Points
+	}
+
+	total := value
+	for range ctx.BonusTiles {
+		items = append(items, ScoreItem{Name: "Flower tiles", Points: mcrFans["Flower tiles"].points})
+		total += mcrFans["Flower tiles"].points
+	}
+
+	return Score{
+		Items: items,
+		Value: value,
+		Total: total,
+	}
+}
+
+func (r *mcrReading) add(name string) {
+	r.fans = append(r.fans, name)
+}
+
+// The fan of the reading, leaving out the fan that are implied by any other fan of the hand.
+func (r *mcrReading) items() []ScoreItem {
+	excluded := make(map[string]bool)
+	for _, name := range r.fans {
+		for _, e := range mcrFans[name].excludes {
+			excluded[e] = true
+		}
+	}
+
+	items := make([]ScoreItem, 0, len(r.fans))
+	for _, name := range r.fans {
+		if !excluded[name] {
+			items = append(items, ScoreItem{Name: name, Points: mcrFans[name].points})
+		}
+	}
+	return items
+}
+
+// Fan that depend on the form of the hand as a whole.
+func (r *mcrReading) formFans() {
+	switch r.d.Form {
+	case FormThirteenOrphans:
+		r.add("Thirteen orphans")
+	case FormSevenPairs:
+		r.add("Seven pairs")
+		if isShiftedPairs(r.d.Pairs) {
+			r.add("Seven shifted pairs")
+		}
+	case FormHonorsAndKnitted:
+		if len(r.tiles)-len(r.d.Knitted) == 7 {
+			r.add("Greater honors and knitted tiles")
+		} else {
+			r.add("Lesser honors and knitted tiles")
+		}
+	case FormKnittedStraight:
+		r.add("Knitted straight")
+	case FormRegular:
+		if IsNineGates(r.hand.Concealed, r.hand.Exposed) {
+			r.add("Nine gates")
+		}
+		if r.isThreeSuitedTerminalChows() {
+			r.add("Three-suited terminal chows")
+		}
+	}
+
+	if r.isPureTerminalChows() {
+		r.add("Pure terminal chows")
+	}
+}
+
+// Fan for pungs of dragons and winds.
+func (r *mcrReading) honorFans() {
+	dragons := r.pungsOf(Tile.IsDragon)
+	pairIsDragon := r.hasPair() && r.d.Pair.IsDragon()
+	switch {
+	case dragons == 3:
+		r.add("Big three dragons")
+	case dragons == 2 && pairIsDragon:
+		r.add("Little three dragons")
+	case dragons == 2:
+		r.add("Two dragon pungs")
+	case dragons == 1:
+		r.add("Dragon pung")
+	}
+
+	winds := r.pungsOf(Tile.IsWind)
+	pairIsWind := r.hasPair() && r.d.Pair.IsWind()
+	switch {
+	case winds == 4:
+		r.add("Big four winds")
+	case winds == 3 && pairIsWind:
+		r.add("Little four winds")
+	case winds == 3:
+		r.add("Big three winds")
+	}
+
+	for _, pung := range r.pungs {
+		if pung == r.ctx.SeatWind.Tile() {
+			r.add("Seat wind")
+		}
+		if pung == r.ctx.PrevalentWind.Tile() {
+			r.add("Prevalent wind")
+		}
+		isValuedWind := pung == r.ctx.SeatWind.Tile() || pung == r.ctx.PrevalentWind.Tile()
+		if pung.IsTerminal() || (pung.IsWind() && !isValuedWind && winds < 3) {
+			r.add("Pung of terminals or honors")
+		}
+	}
+}
+
+// Fan for the sets of the hand and how they combine.
+func (r *mcrReading) setFans() {
+	kongs := r.meldedKongs + r.concealedKongs
+	switch {
+	case kongs == 4:
+		r.add("Four kongs")
+	case kongs == 3:
+		r.add("Three kongs")
+	case r.concealedKongs == 2:
+		r.add("Two concealed kongs")
+	case r.meldedKongs == 2:
+		r.add("Two melded kongs")
+	default:
+		if r.concealedKongs == 1 {
+			r.add("Concealed kong")
+		}
+		if r.meldedKongs == 1 {
+			r.add("Melded kong")
+		}
+	}
+
+	switch r.concealedPungs {
+	case 4:
+		r.add("Four concealed pungs")
+	case 3:
+		r.add("Three concealed pungs")
+	case 2:
+		r.add("Two concealed pungs")
+	}
+
+	if r.d.Form == FormRegular && len(r.chows) == 0 {
+		r.add("All pungs")
+	}
+	if r.d.Form == FormRegular && len(r.chows) == 4 && r.d.Pair.IsSuit() {
+		r.add("All chows")
+	}
+
+	counts := make(map[Tile]int)
+	for _, tile := range r.tiles {
+		counts[tile]++
+	}
+	for _, tile := range sortedTiles(r.tiles) {
+		if counts[tile] == 4 && !r.hasKong(tile) {
+			r.add("Tile hog")
+			counts[tile] = 0
+		}
+	}
+
+	suitedPungs := make([]Tile, 0, len(r.pungs))
+	for _, pung := range r.pungs {
+		if pung.IsSuit() {
+			suitedPungs = append(suitedPungs, pung)
+		}
+	}
+
+	r.combinationFans(r.chows, mcrChowCombinations)
+	r.combinationFans(suitedPungs, mcrPungCombinations)
+}
+
+// mcrCombination is a fan formed by a number of chows or pungs.
+type mcrCombination struct {
+	name string
+	size int
+	// Whether the sets form the fan. Chows are given by their first tile, the sets are ordered by number and then by suit.
+	matches func(sets []Tile) bool
+}
+
+var mcrChowCombinations = []mcrCombination{
+	{"Quadruple chow", 4, func(c []Tile) bool { return identical(c) }},
+	{"Four pure shifted chows", 4, func(c []Tile) bool { return sameSuit(c) && (stepped(c, 1) || stepped(c, 2)) }},
+	{"Pure triple chow", 3, func(c []Tile) bool { return identical(c) }},
+	{"Pure straight", 3, func(c []Tile) bool { return sameSuit(c) && stepped(c, 3) && c[0]%10 == 1 }},
+	{"Pure shifted chows", 3, func(c []Tile) bool { return sameSuit(c) && (stepped(c, 1) || stepped(c, 2)) }},
+	{"Mixed straight", 3, func(c []Tile) bool { return differentSuits(c) && stepped(numbers(c), 3) && c[0]%10 == 1 }},
+	{"Mixed triple chow", 3, func(c []Tile) bool { return differentSuits(c) && identical(numbers(c)) }},
+	{"Mixed shifted chows", 3, func(c []Tile) bool { return differentSuits(c) && stepped(numbers(c), 1) }},
+	{"Pure double chow", 2, func(c []Tile) bool { return identical(c) }},
+	{"Mixed double chow", 2, func(c []Tile) bool { return differentSuits(c) && identical(numbers(c)) }},
+	{"Short straight", 2, func(c []Tile) bool { return sameSuit(c) && stepped(c, 3) }},
+	{"Two terminal chows", 2, func(c []Tile) bool { return sameSuit(c) && stepped(c, 6) }},
+}
+
+var mcrPungCombinations = []mcrCombination{
+	{"Four pure shifted pungs", 4, func(p []Tile) bool { return sameSuit(p) && stepped(p, 1) }},
+	{"Pure shifted pungs", 3, func(p []Tile) bool { return sameSuit(p) && stepped(p, 1) }},
+	{"Triple pung", 3, func(p []Tile) bool { return differentSuits(p) && identical(numbers(p)) }},
+	{"Mixed shifted pungs", 3, func(p []Tile) bool { return differentSuits(p) && stepped(numbers(p), 1) }},
+	{"Double pung", 2, func(p []Tile) bool { return differentSuits(p) && identical(numbers(p)) }},
+}
+
+// Add the fan formed by combining the given sets, trying the most valuable combinations first.
+// Sets that were combined into a fan are joined, and a fan can only combine sets that are not joined yet.
+// This way every set is combined with the others at most once, so n sets form at most n-1 fan.
+func (r *mcrReading) combinationFans(sets []Tile, combinations []mcrCombination) {
+	group := make([]int, len(sets))
+	for i := range group {
+		group[i] = i
+	}
+
+	for _, combination := range combinations {
+		for _, indices := range subsets(len(sets), combination.size) {
+			seen := make(map[int]bool, len(indices))
+			tiles := make([]Tile, len(indices))
+			for i, index := range indices {
+				seen[group[index]] = true
+				tiles[i] = sets[index]
+			}
+			if len(seen) != len(indices) {
+				continue
+			}
+
+			sort.Slice(tiles, func(i, j int) bool {
+				if tiles[i]%10 != tiles[j]%10 {
+					return tiles[i]%10 < tiles[j]%10
+				}
+				return tiles[i] < tiles[j]
+			})
+			if !combination.matches(tiles) {
+				continue
+			}
+
+			r.add(combination.name)
+			joined := group[indices[0]]
+			for _, index := range indices {
+				from := group[index]
+				for i := range group {
+					if group[i] == from {
+						group[i] = joined
+					}
+				}
+			}
+		}
+	}
+}
+
+// Fan for the kinds of tiles in the hand.
+func (r *mcrReading) tileFans() {
+	if r.all(isGreen) {
+		r.add("All green")
+	}
+
+	switch {
+	case r.all(Tile.IsTerminal):
+		r.add("All terminals")
+	case r.all(Tile.IsHonor):
+		r.add("All honors")
+	case r.all(Tile.IsTerminalOrHonor) && r.d.Form != FormThirteenOrphans:
+		r.add("All terminals and honors")
+	}
+
+	if r.d.Form == FormRegular && len(r.chows) == 0 && r.all(isEven) {
+		r.add("All even pungs")
+	}
+
+	suits := make(map[Tile]bool)
+	hasHonors := false
+	for _, tile := range r.tiles {
+		if tile.IsHonor() {
+			hasHonors = true
+		} else {
+			suits[tile/10] = true
+		}
+	}
+	switch {
+	case len(suits) == 1 && !hasHonors:
+		r.add("Full flush")
+	case len(suits) == 1:
+		r.add("Half flush")
+	case len(suits) == 2:
+		r.add("One voided suit")
+	}
+	if !hasHonors {
+		r.add("No honors")
+	}
+	if len(suits) == 3 && r.any(Tile.IsWind) && r.any(Tile.IsDragon) {
+		r.add("All types")
+	}
+
+	switch {
+	case r.all(inRange(7, 9)):
+		r.add("Upper tiles")
+	case r.all(inRange(4, 6)):
+		r.add("Middle tiles")
+	case r.all(inRange(1, 3)):
+		r.add("Lower tiles")
+	case r.all(inRange(6, 9)):
+		r.add("Upper four")
+	case r.all(inRange(1, 4)):
+		r.add("Lower four")
+	}
+
+	if r.all(Tile.IsSimple) {
+		r.add("All simples")
+	}
+	if r.all(isReversible) {
+		r.add("Reversible tiles")
+	}
+	if r.everyGroupHas(Tile.IsTerminalOrHonor) {
+		r.add("Outside hand")
+	}
+	if r.everyGroupHas(func(t Tile) bool { return t.IsSuit() && t%10 == 5 }) {
+		r.add("All fives")
+	}
+}
+
+// Fan for the way the hand was won.
+func (r *mcrReading) winFans() {
+	switch {
+	case r.concealed && r.ctx.IsSelfDrawn():
+		r.add("Fully concealed hand")
+	case r.concealed:
+		r.add("Concealed hand")
+	}
+
+	if r.d.Form == FormRegular && len(r.d.Concealed) == 0 && r.concealedKongs == 0 && !r.ctx.IsSelfDrawn() {
+		r.add("Melded hand")
+	}
+
+	if r.ctx.IsSelfDrawn() {
+		r.add("Self drawn")
+	}
+
+	if r.ctx.LastTile {
+		if r.ctx.IsSelfDrawn() {
+			r.add("Last tile draw")
+		} else {
+			r.add("Last tile claim")
+		}
+	}
+	if r.ctx.ReplacementTile {
+		r.add("Out with replacement tile")
+	}
+	if r.ctx.RobbingKong {
+		r.add("Robbing the kong")
+	}
+	if r.ctx.LastOfKind {
+		r.add("Last tile")
+	}
+
+	if r.waitsOnSingleTile() {
+		switch r.wait {
+		case WaitPair:
+			r.add("Single wait")
+		case WaitEdge:
+			r.add("Edge wait")
+		case WaitClosed:
+			r.add("Closed wait")
+		}
+	}
+}
+
+// Whether the winning tile was the only tile that could complete the hand. Only then the wait is worth a fan.
+func (r *mcrReading) waitsOnSingleTile() bool {
+	counts := countsOf(r.hand.Concealed)
+	counts[r.ctx.WinningTile]--
+
+	waits := 0
+	for _, tile := range allTileKinds() {
+		counts[tile]++
+		if counts.isComplete() || (r.d.Form == FormKnittedStraight && counts.isKnittedStraight()) {
+			waits++
+		}
+		counts[tile]--
+	}
+	return waits == 1
+}
+
+// 11223355778899 in a single suit.
+func (r *mcrReading) isPureTerminalChows() bool {
+	if len(r.tiles) != 14 || !r.all(Tile.IsSuit) {
+		return false
+	}
+	suit := r.tiles[0] / 10 * 10
+	counts := make(map[Tile]int)
+	for _, tile := range r.tiles {
+		counts[tile]++
+	}
+	for _, number := range []Tile{1, 2, 3, 5, 7, 8, 9} {
+		if counts[suit+number] != 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// 123 and 789 in two suits, and a pair of fives in the third.
+func (r *mcrReading) isThreeSuitedTerminalChows() bool {
+	if len(r.chows) != 4 || !r.d.Pair.IsSuit() || r.d.Pair%10 != 5 {
+		return false
+	}
+	pairSuit := r.d.Pair / 10 * 10
+	for suit := Bamboo1; suit <= Characters1; suit += 10 {
+		if suit == pairSuit {
+			continue
+		}
+		if !r.hasChow(suit) || !r.hasChow(suit+6) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *mcrReading) hasPair() bool {
+	return r.d.Form == FormRegular || r.d.Form == FormKnittedStraight
+}
+
+func (r *mcrReading) hasChow(first Tile) bool {
+	for _, c := range r.chows {
+		if c == first {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *mcrReading) hasKong(tile Tile) bool {
+	for _, set := range r.d.Exposed {
+		if k, isKong := set.(Kong); isKong && k.Tile == tile {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *mcrReading) pungsOf(is func(Tile) bool) int {
+	n := 0
+	for _, tile := range r.pungs {
+		if is(tile) {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *mcrReading) all(is func(Tile) bool) bool {
+	for _, tile := range r.tiles {
+		if !is(tile) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *mcrReading) any(is func(Tile) bool) bool {
+	for _, tile := range r.tiles {
+		if is(tile) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether every set and the pair of a regular hand contain a tile for which is holds.
+func (r *mcrReading) everyGroupHas(is func(Tile) bool) bool {
+	if r.d.Form != FormRegular || !is(r.d.Pair) {
+		return false
+	}
+	for _, first := range r.chows {
+		if !is(first) && !is(first+1) && !is(first+2) {
+			return false
+		}
+	}
+	for _, tile := range r.pungs {
+		if !is(tile) {
+			return false
+		}
+	}
+	return true
+}
+
+// Seven pairs of consecutive tiles in a single suit.
+func isShiftedPairs(pairs []Tile) bool {
+	if len(pairs) != 7 || !pairs[0].IsSuit() || pairs[0]%10 > 3 {
+		return false
+	}
+	for i, pair := range pairs {
+		if pair != pairs[0]+Tile(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Suit tiles with an even number.
+func isEven(t Tile) bool {
+	return t.IsSuit() && t%10%2 == 0
+}
+
+// Suit tiles numbered from low to high, inclusive.
+func inRange(low Tile, high Tile) func(Tile) bool {
+	return func(t Tile) bool {
+		return t.IsSuit() && t%10 >= low && t%10 <= high
+	}
+}
+
+// Tiles that look the same when turned upside down.
+func isReversible(t Tile) bool {
+	switch t {
+	case Circles1, Circles2, Circles3, Circles4, Circles5, Circles8, Circles9,
+		Bamboo2, Bamboo4, Bamboo5, Bamboo6, Bamboo8, Bamboo9, WhiteDragon:
+		return true
+	}
+	return false
+}
+
+func identical(tiles []Tile) bool {
+	for _, t := range tiles {
+		if t != tiles[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameSuit(tiles []Tile) bool {
+	for _, t := range tiles {
+		if t/10 != tiles[0]/10 {
+			return false
+		}
+	}
+	return true
+}
+
+func differentSuits(tiles []Tile) bool {
+	seen := make(map[Tile]bool, len(tiles))
+	for _, t := range tiles {
+		if seen[t/10] {
+			return false
+		}
+		seen[t/10] = true
+	}
+	return true
+}
+
+// Whether every tile is the previous one plus the step, in the given order.
+func stepped(tiles []Tile, step Tile) bool {
+	for i := 1; i < len(tiles); i++ {
+		if tiles[i] != tiles[i-1]+step {
+			return false
+		}
+	}
+	return true
+}
+
+func numbers(tiles []Tile) []Tile {
+	n := make([]Tile, len(tiles))
+	for i, t := range tiles {
+		n[i] = t % 10
+	}
+	return n
+}
+
+// All ways to choose k out of n indices, in increasing order.
+func subsets(n int, k int) [][]int {
+	if k == 0 {
+		return [][]int{{}}
+	}
+	result := make([][]int, 0)
+	for last := k - 1; last < n; last++ {
+		for _, rest := range subsets(last, k-1) {
+			result = append(result, append(rest, last))
+		}
+	}
+	return result
+}
+
+// The winner receives the base payment from every other player, and the hand value from the discarder or, when self drawn, from everyone.
+func (s MCRScorer) Payments(score Score, ctx WinContext) map[int]int {
+	payments := make(map[int]int, len(ctx.Seats))
+
+	for _, seat := range ctx.Seats {
+		if seat == ctx.Winner {
+			continue
+		}
+		amount := s.BasePayment
+		if ctx.IsSelfDrawn() || seat == ctx.Discarder {
+			amount += score.Total
+		}
+		payments[seat] -= amount
+		payments[ctx.Winner] += amount
+	}
+
+	return payments
+}
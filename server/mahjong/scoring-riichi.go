@@ -0,0 +1,605 @@
+package mahjong
+
+// RiichiScorer values hands with the han and fu of Japanese riichi mahjong.
+// A hand needs at least one yaku to win, dora only add to a hand that already has one.
+type RiichiScorer struct{}
+
+const (
+	riichiMangan    = 2000
+	riichiHaneman   = 3000
+	riichiBaiman    = 4000
+	riichiSanbaiman = 6000
+	riichiYakuman   = 8000
+)
+
+func (s RiichiScorer) Score(hand WinningHand, ctx WinContext) Score {
+	return bestScore(hand.Decompositions, func(d Decomposition) Score {
+		var best Score
+		for i, wait := range d.Waits(ctx.WinningTile) {
+			score := s.scoreReading(hand, d, wait, ctx)
+			if i == 0 || score.Total > best.Total {
+				best = score
+			}
+		}
+		return best
+	})
+}
+
+// A hand without yaku scores zero.
+func (s RiichiScorer) AllowsWin(hand WinningHand, ctx WinContext) bool {
+	return s.Score(hand, ctx).Total > 0
+}
+
+// The reading of a decomposition, split into the facts the yaku and fu are based on.
+type riichiReading struct {
+	d      Decomposition
+	wait   Wait
+	ctx    WinContext
+	closed bool
+	tiles  []Tile
+	chows  []Tile
+	pungs  []Tile
+	kongs  int
+	// Pungs and kongs that were formed without claiming a tile.
+	concealedPungs int
+}
+
+func newRiichiReading(hand WinningHand, d Decomposition, wait Wait, ctx WinContext) riichiReading {
+	r := riichiReading{
+		d:      d,
+		wait:   wait,
+		ctx:    ctx,
+		closed: true,
+		tiles:  hand.Concealed.list(),
+	}
+
+	completedByDiscard := wait == WaitPung && !ctx.IsSelfDrawn()
+	for _, set := range d.Concealed {
+		switch c := set.(type) {
+		case Chow:
+			r.chows = append(r.chows, c.FirstTile)
+		case Pung:
+			r.pungs = append(r.pungs, c.Tile)
+			if completedByDiscard && c.Tile == ctx.WinningTile {
+				// a pung completed by a discard counts as exposed
+				completedByDiscard = false
+				continue
+			}
+			r.concealedPungs++
+		}
+	}
+
+	for _, set := range d.Exposed {
+		switch c := set.(type) {
+		case Chow:
+			r.chows = append(r.chows, c.FirstTile)
+			r.closed = false
+			r.tiles = append(r.tiles, c.FirstTile, c.FirstTile+1, c.FirstTile+2)
+		case Pung:
+			r.pungs = append(r.pungs, c.Tile)
+			r.closed = false
+			r.tiles = append(r.tiles, c.Tile, c.Tile, c.Tile)
+		case Kong:
+			r.pungs = append(r.pungs, c.Tile)
+			r.kongs++
+			if c.Concealed {
+				r.concealedPungs++
+			} else {
+				r.closed = false
+			}
+			r.tiles = append(r.tiles, c.Tile, c.Tile, c.Tile, c.Tile)
+		}
+	}
+
+	return r
+}
+
+func (s RiichiScorer) scoreReading(hand WinningHand, d Decomposition, wait Wait, ctx WinContext) Score {
+	r := newRiichiReading(hand, d, wait, ctx)
+
+	if yakuman := r.yakuman(hand); len(yakuman) > 0 {
+		return Score{
+			Items: yakuman,
+			Value: 13 * len(yakuman),
+			Total: riichiYakuman * len(yakuman),
+		}
+	}
+
+	items := r.yaku()
+	if len(items) == 0 {
+		return Score{Items: []ScoreItem{}}
+	}
+
+	if n := r.count(ctx.Dora); n > 0 {
+		items = append(items, ScoreItem{Name: "Dora", Doubles: n})
+	}
+	if n := r.count(ctx.UraDora); n > 0 {
+		items = append(items, ScoreItem{Name: "Ura dora", Doubles: n})
+	}
+	if n := bonusDora(ctx); n > 0 {
+		items = append(items, ScoreItem{Name: "North dora", Doubles: n})
+	}
+	if ctx.RedFives > 0 {
+		items = append(items, ScoreItem{Name: "Red fives", Doubles: ctx.RedFives})
+	}
+
+	fu := r.fu()
+	items = append(items, ScoreItem{Name: "Fu", Points: fu})
+
+	han := 0
+	for _, item := range items {
+		han += item.Doubles
+	}
+
+	return Score{
+		Items: items,
+		Value: han,
+		Total: riichiBasePoints(han, fu),
+	}
+}
+
+// The base points of a hand, which the payments are multiples of.
+func riichiBasePoints(han int, fu int) int {
+	switch {
+	case han >= 13:
+		return riichiYakuman
+	case han >= 11:
+		return riichiSanbaiman
+	case han >= 8:
+		return riichiBaiman
+	case han >= 6:
+		return riichiHaneman
+	case han >= 5:
+		return riichiMangan
+	}
+
+	base := fu * (1 << uint(han+2))
+	if base > riichiMangan {
+		return riichiMangan
+	}
+	return base
+}
+
+func (r riichiReading) yakuman(hand WinningHand) []ScoreItem {
+	items := make([]ScoreItem, 0)
+	add := func(name string) {
+		items = append(items, ScoreItem{Name: name, Doubles: 13})
+	}
+
+	if r.d.Form == FormThirteenOrphans {
+		add("Thirteen orphans")
+	}
+	if r.closed && IsNineGates(hand.Concealed, hand.Exposed) {
+		add("Nine gates")
+	}
+	if r.ctx.FirstDraw {
+		if r.ctx.Winner == r.ctx.Dealer {
+			add("Blessing of heaven")
+		} else {
+			add("Blessing of earth")
+		}
+	}
+	if r.d.Form == FormThirteenOrphans {
+		return items
+	}
+
+	if r.concealedPungs == 4 {
+		add("Four concealed pungs")
+	}
+	if r.pungsOf(Tile.IsDragon) == 3 {
+		add("Big three dragons")
+	}
+	windPungs := r.pungsOf(Tile.IsWind)
+	if windPungs == 4 {
+		add("Big four winds")
+	} else if windPungs == 3 && r.d.Form == FormRegular && r.d.Pair.IsWind() {
+		add("Little four winds")
+	}
+	if r.all(Tile.IsHonor) {
+		add("All honors")
+	}
+	if r.all(Tile.IsTerminal) {
+		add("All terminals")
+	}
+	if r.all(isGreen) {
+		add("All green")
+	}
+	if r.kongs == 4 {
+		add("Four kongs")
+	}
+
+	return items
+}
+
+func (r riichiReading) yaku() []ScoreItem {
+	items := make([]ScoreItem, 0)
+	add := func(name string, closedHan int, openHan int) {
+		han := closedHan
+		if !r.closed {
+			han = openHan
+		}
+		if han > 0 {
+			items = append(items, ScoreItem{Name: name, Doubles: han})
+		}
+	}
+
+	switch r.ctx.Riichi {
+	case RiichiDeclared:
+		add("Riichi", 1, 0)
+	case RiichiDoubleDeclared:
+		add("Double riichi", 2, 0)
+	}
+	if r.ctx.Riichi != RiichiNone && r.ctx.Ippatsu {
+		add("Ippatsu", 1, 0)
+	}
+	if r.ctx.IsSelfDrawn() {
+		add("Fully concealed hand", 1, 0)
+	}
+	if r.isPinfu() {
+		add("Pinfu", 1, 0)
+	}
+	if r.ctx.LastTile {
+		if r.ctx.IsSelfDrawn() {
+			add("Under the sea", 1, 1)
+		} else {
+			add("Under the river", 1, 1)
+		}
+	}
+	if r.ctx.ReplacementTile {
+		add("After a kong", 1, 1)
+	}
+	if r.ctx.RobbingKong {
+		add("Robbing the kong", 1, 1)
+	}
+	if r.all(Tile.IsSimple) {
+		add("All simples", 1, 1)
+	}
+
+	for _, pung := range r.pungs {
+		if pung.IsDragon() {
+			add("Dragon pung", 1, 1)
+		}
+		if pung == r.ctx.SeatWind.Tile() {
+			add("Seat wind", 1, 1)
+		}
+		if pung == r.ctx.PrevalentWind.Tile() {
+			add("Prevalent wind", 1, 1)
+		}
+	}
+
+	switch identical := r.identicalChows(); {
+	case identical == 2:
+		add("Twice pure double chow", 3, 0)
+	case identical == 1:
+		add("Pure double chow", 1, 0)
+	}
+
+	if r.d.Form == FormSevenPairs {
+		add("Seven pairs", 2, 2)
+	}
+
+	if r.hasMixedTripleChow() {
+		add("Mixed triple chow", 2, 1)
+	}
+	if r.hasPureStraight() {
+		add("Pure straight", 2, 1)
+	}
+	if r.d.Form == FormRegular && len(r.chows) == 0 {
+		add("All pungs", 2, 2)
+	}
+	if r.concealedPungs == 3 {
+		add("Three concealed pungs", 2, 2)
+	}
+	if r.hasTriplePung() {
+		add("Triple pung", 2, 2)
+	}
+	if r.kongs == 3 {
+		add("Three kongs", 2, 2)
+	}
+	if r.pungsOf(Tile.IsDragon) == 2 && r.d.Form == FormRegular && r.d.Pair.IsDragon() {
+		add("Little three dragons", 2, 2)
+	}
+
+	switch {
+	case r.all(Tile.IsTerminalOrHonor):
+		add("All terminals and honors", 2, 2)
+	case r.everyGroupHas(Tile.IsTerminal):
+		add("Terminals in all sets", 3, 2)
+	case r.everyGroupHas(Tile.IsTerminalOrHonor):
+		add("Terminals or honors in all sets", 2, 1)
+	}
+
+	switch handSuits(r.d) {
+	case suitsPure:
+		add("Full flush", 6, 5)
+	case suitsMixed:
+		add("Half flush", 3, 2)
+	}
+
+	return items
+}
+
+func (r riichiReading) fu() int {
+	if r.d.Form == FormSevenPairs {
+		return 25
+	}
+
+	if r.isPinfu() && r.ctx.IsSelfDrawn() {
+		return 20
+	}
+
+	fu := 20
+
+	if r.closed && !r.ctx.IsSelfDrawn() {
+		fu += 10
+	}
+	if r.ctx.IsSelfDrawn() {
+		fu += 2
+	}
+	if r.wait == WaitPair || r.wait == WaitEdge || r.wait == WaitClosed {
+		fu += 2
+	}
+
+	if r.d.Pair.IsDragon() {
+		fu += 2
+	}
+	if r.d.Pair == r.ctx.SeatWind.Tile() {
+		fu += 2
+	}
+	if r.d.Pair == r.ctx.PrevalentWind.Tile() {
+		fu += 2
+	}
+
+	completedByDiscard := r.wait == WaitPung && !r.ctx.IsSelfDrawn()
+	for _, set := range r.d.Concealed {
+		if p, isPung := set.(Pung); isPung {
+			concealed := true
+			if completedByDiscard && p.Tile == r.ctx.WinningTile {
+				concealed = false
+				completedByDiscard = false
+			}
+			fu += riichiSetFu(p.Tile, concealed, false)
+		}
+	}
+	for _, set := range r.d.Exposed {
+		switch c := set.(type) {
+		case Pung:
+			fu += riichiSetFu(c.Tile, false, false)
+		case Kong:
+			fu += riichiSetFu(c.Tile, c.Concealed, true)
+		}
+	}
+
+	if !r.closed && fu == 20 {
+		// an open hand without any fu is still worth 30
+		fu = 30
+	}
+
+	return (fu + 9) / 10 * 10
+}
+
+func riichiSetFu(tile Tile, concealed bool, kong bool) int {
+	fu := 2
+	if concealed {
+		fu *= 2
+	}
+	if tile.IsTerminalOrHonor() {
+		fu *= 2
+	}
+	if kong {
+		fu *= 4
+	}
+	return fu
+}
+
+// A closed hand of four chows with an open wait and a pair that is not worth anything.
+func (r riichiReading) isPinfu() bool {
+	if !r.closed || r.d.Form != FormRegular || len(r.chows) != 4 || r.wait != WaitOpen {
+		return false
+	}
+	return !r.d.Pair.IsDragon() && r.d.Pair != r.ctx.SeatWind.Tile() && r.d.Pair != r.ctx.PrevalentWind.Tile()
+}
+
+// Number of tiles in the hand that are one of the given tiles, counting each tile once for every time it appears in the list.
+func (r riichiReading) count(dora []Tile) int {
+	n := 0
+	for _, d := range dora {
+		for _, tile := range r.tiles {
+			if tile == d {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Set aside north winds count as a dora each, and once more for every indicator that points at them.
+func bonusDora(ctx WinContext) int {
+	n := len(ctx.BonusTiles)
+	for _, bonus := range ctx.BonusTiles {
+		for _, d := range ctx.Dora {
+			if bonus == d {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (r riichiReading) all(is func(Tile) bool) bool {
+	for _, tile := range r.tiles {
+		if !is(tile) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r riichiReading) pungsOf(is func(Tile) bool) int {
+	n := 0
+	for _, tile := range r.pungs {
+		if is(tile) {
+			n++
+		}
+	}
+	return n
+}
+
+// Whether every set and the pair contain a tile for which is holds, with at least one chow among the sets.
+func (r riichiReading) everyGroupHas(is func(Tile) bool) bool {
+	if r.d.Form != FormRegular || len(r.chows) == 0 {
+		return false
+	}
+	if !is(r.d.Pair) {
+		return false
+	}
+	for _, first := range r.chows {
+		if !is(first) && !is(first+2) {
+			return false
+		}
+	}
+	for _, tile := range r.pungs {
+		if !is(tile) {
+			return false
+		}
+	}
+	return true
+}
+
+// Number of pairs of identical concealed chows, only counted in a closed hand.
+func (r riichiReading) identicalChows() int {
+	if !r.closed {
+		return 0
+	}
+	counts := make(map[Tile]int)
+	for _, first := range r.chows {
+		counts[first]++
+	}
+	identical := 0
+	for _, n := range counts {
+		identical += n / 2
+	}
+	return identical
+}
+
+func (r riichiReading) hasMixedTripleChow() bool {
+	for _, first := range r.chows {
+		number := first % 10
+		if r.hasChow(Bamboo1-1+number) && r.hasChow(Circles1-1+number) && r.hasChow(Characters1-1+number) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r riichiReading) hasPureStraight() bool {
+	for suit := Bamboo1; suit <= Characters1; suit += 10 {
+		if r.hasChow(suit) && r.hasChow(suit+3) && r.hasChow(suit+6) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r riichiReading) hasTriplePung() bool {
+	for _, tile := range r.pungs {
+		if !tile.IsSuit() {
+			continue
+		}
+		number := tile % 10
+		if r.hasPung(Bamboo1-1+number) && r.hasPung(Circles1-1+number) && r.hasPung(Characters1-1+number) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r riichiReading) hasChow(first Tile) bool {
+	for _, c := range r.chows {
+		if c == first {
+			return true
+		}
+	}
+	return false
+}
+
+func (r riichiReading) hasPung(tile Tile) bool {
+	for _, p := range r.pungs {
+		if p == tile {
+			return true
+		}
+	}
+	return false
+}
+
+// Tiles that only have green in their design.
+func isGreen(t Tile) bool {
+	switch t {
+	case Bamboo2, Bamboo3, Bamboo4, Bamboo6, Bamboo8, GreenDragon:
+		return true
+	}
+	return false
+}
+
+// Payments are multiples of the base points, rounded up to 100. The dealer receives and pays double.
+// Every repeat counter adds 300 points, and the winner also collects the riichi deposits on the table.
+func (s RiichiScorer) Payments(score Score, ctx WinContext) map[int]int {
+	payments := make(map[int]int, len(ctx.Seats))
+
+	pay := func(seat int, amount int) {
+		payments[seat] -= amount
+		payments[ctx.Winner] += amount
+	}
+
+	if !ctx.IsSelfDrawn() {
+		multiplier := 4
+		if ctx.Winner == ctx.Dealer {
+			multiplier = 6
+		}
+		pay(ctx.Discarder, roundUpToHundred(multiplier*score.Total)+300*ctx.Repeats)
+		return payments
+	}
+
+	for _, seat := range ctx.Seats {
+		if seat == ctx.Winner {
+			continue
+		}
+		multiplier := 1
+		if seat == ctx.Dealer || ctx.Winner == ctx.Dealer {
+			multiplier = 2
+		}
+		pay(seat, roundUpToHundred(multiplier*score.Total)+100*ctx.Repeats)
+	}
+
+	return payments
+}
+
+// When the wall is exhausted the players that are not tenpai pay 3000 points in total to the players that are.
+func (s RiichiScorer) DrawPayments(tenpai []int, seats []int) map[int]int {
+	payments := make(map[int]int, len(seats))
+
+	if len(tenpai) == 0 || len(tenpai) == len(seats) {
+		return payments
+	}
+
+	isTenpai := make(map[int]bool, len(tenpai))
+	for _, s := range tenpai {
+		isTenpai[s] = true
+	}
+
+	const total = 3000
+	noten := len(seats) - len(tenpai)
+	for _, s := range seats {
+		if isTenpai[s] {
+			payments[s] += total / len(tenpai)
+		} else {
+			payments[s] -= total / noten
+		}
+	}
+
+	return payments
+}
+
+func roundUpToHundred(points int) int {
+	return (points + 99) / 100 * 100
+}
@@ -0,0 +1,143 @@
+package mahjong
+
+// SichuanScorer values hands with the fan of Sichuan bloody battle. Every fan doubles the base amount, up to a limit.
+type SichuanScorer struct {
+	// Amount a hand without fan is worth.
+	Base int
+	// Maximum number of fan a hand is worth.
+	LimitFan int
+}
+
+func (s SichuanScorer) Score(hand WinningHand, ctx WinContext) Score {
+	roots := s.roots(hand)
+
+	return bestScore(hand.Decompositions, func(d Decomposition) Score {
+		return s.scoreReading(d, roots, ctx)
+	})
+}
+
+func (s SichuanScorer) scoreReading(d Decomposition, roots int, ctx WinContext) Score {
+	items := make([]ScoreItem, 0)
+	add := func(name string, fan int) {
+		items = append(items, ScoreItem{Name: name, Doubles: fan})
+	}
+
+	if ctx.FirstDraw {
+		if ctx.Winner == ctx.Dealer {
+			add("Heavenly hand", s.LimitFan)
+		} else {
+			add("Earthly hand", s.LimitFan)
+		}
+	}
+
+	chows := 0
+	for _, set := range d.Sets() {
+		if _, isChow := set.(Chow); isChow {
+			chows++
+		}
+	}
+
+	switch {
+	case d.Form == FormSevenPairs:
+		add("Seven pairs", 2)
+	case chows == 0:
+		add("All pungs", 1)
+	}
+	if handSuits(d) == suitsPure {
+		add("Full flush", 2)
+	}
+	for i := 0; i < roots; i++ {
+		add("Root", 1)
+	}
+
+	if ctx.IsSelfDrawn() {
+		add("Self drawn", 1)
+	}
+	if ctx.ReplacementTile {
+		add("Winning on a replacement tile", 1)
+	}
+	if ctx.RobbingKong {
+		add("Robbing the kong", 1)
+	}
+	if ctx.LastTile {
+		add("Winning on the last tile", 1)
+	}
+
+	fan := 0
+	for _, item := range items {
+		fan += item.Doubles
+	}
+	if fan > s.LimitFan {
+		fan = s.LimitFan
+	}
+
+	return Score{
+		Items: items,
+		Value: fan,
+		Total: s.Base << uint(fan),
+	}
+}
+
+// Number of kinds of which the hand holds all four tiles, either as a kong or spread over the hand.
+func (s SichuanScorer) roots(hand WinningHand) int {
+	counts := make(map[Tile]int)
+	for _, tile := range hand.Concealed.list() {
+		counts[tile]++
+	}
+	for _, c := range hand.Exposed.combinations {
+		switch comb := c.(type) {
+		case Chow:
+			for i := Tile(0); i < 3; i++ {
+				counts[comb.FirstTile+i]++
+			}
+		case Pung:
+			counts[comb.Tile] += 3
+		case Kong:
+			counts[comb.Tile] += 4
+		}
+	}
+
+	roots := 0
+	for _, count := range counts {
+		if count == 4 {
+			roots++
+		}
+	}
+	return roots
+}
+
+// The discarder pays the hand value. When self drawn every player that is still in the hand pays it.
+func (s SichuanScorer) Payments(score Score, ctx WinContext) map[int]int {
+	payments := make(map[int]int, len(ctx.Seats))
+
+	for _, seat := range ctx.Seats {
+		if seat == ctx.Winner || (!ctx.IsSelfDrawn() && seat != ctx.Discarder) {
+			continue
+		}
+		payments[seat] -= score.Total
+		payments[ctx.Winner] += score.Total
+	}
+
+	return payments
+}
+
+// When the wall is exhausted, the players still in the hand that are not one tile away from winning pay the base amount to every player that is.
+func (s SichuanScorer) DrawPayments(tenpai []int, seats []int) map[int]int {
+	isTenpai := make(map[int]bool, len(tenpai))
+	for _, seat := range tenpai {
+		isTenpai[seat] = true
+	}
+
+	payments := make(map[int]int, len(seats))
+	for _, payer := range seats {
+		if isTenpai[payer] {
+			continue
+		}
+		for _, receiver := range tenpai {
+			payments[payer] -= s.Base
+			payments[receiver] += s.Base
+		}
+	}
+
+	return payments
+}
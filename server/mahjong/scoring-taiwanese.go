@@ -0,0 +1,211 @@
+package mahjong
+
+// TaiwaneseScorer values sixteen tile hands with the tai of Taiwanese mahjong.
+// Every hand is worth a base amount plus an amount per tai, so there is no minimum to win.
+type TaiwaneseScorer struct {
+	// Amount every winning hand is worth.
+	Base int
+	// Amount added for every tai.
+	PerTai int
+}
+
+func (s TaiwaneseScorer) Score(hand WinningHand, ctx WinContext) Score {
+	return bestScore(hand.Decompositions, func(d Decomposition) Score {
+		var best Score
+		for i, wait := range d.Waits(ctx.WinningTile) {
+			score := s.scoreReading(d, wait, ctx)
+			if i == 0 || score.Total > best.Total {
+				best = score
+			}
+		}
+		return best
+	})
+}
+
+func (s TaiwaneseScorer) scoreReading(d Decomposition, wait Wait, ctx WinContext) Score {
+	items := make([]ScoreItem, 0)
+	add := func(name string, tai int) {
+		items = append(items, ScoreItem{Name: name, Doubles: tai})
+	}
+
+	var pungs []Tile
+	chows := 0
+	concealedPungs := 0
+	isConcealed := true
+	allMelded := len(d.Concealed) == 0
+
+	completedByDiscard := wait == WaitPung && !ctx.IsSelfDrawn()
+	for _, set := range d.Concealed {
+		switch c := set.(type) {
+		case Chow:
+			chows++
+		case Pung:
+			pungs = append(pungs, c.Tile)
+			if completedByDiscard && c.Tile == ctx.WinningTile {
+				// a pung completed by a discard counts as exposed
+				completedByDiscard = false
+				continue
+			}
+			concealedPungs++
+		}
+	}
+	for _, set := range d.Exposed {
+		switch c := set.(type) {
+		case Chow:
+			chows++
+			isConcealed = false
+		case Pung:
+			pungs = append(pungs, c.Tile)
+			isConcealed = false
+		case Kong:
+			pungs = append(pungs, c.Tile)
+			if c.Concealed {
+				concealedPungs++
+				allMelded = false
+			} else {
+				isConcealed = false
+			}
+		}
+	}
+
+	if ctx.FirstDraw {
+		if ctx.Winner == ctx.Dealer {
+			add("Heavenly hand", 16)
+		} else {
+			add("Earthly hand", 16)
+		}
+	}
+
+	if ctx.Winner == ctx.Dealer {
+		add("Dealer", 1)
+		if ctx.Repeats > 0 {
+			add("Dealer repeats", 2*ctx.Repeats)
+		}
+	}
+
+	switch {
+	case isConcealed && ctx.IsSelfDrawn():
+		add("Concealed self drawn", 3)
+	case isConcealed:
+		add("Concealed hand", 1)
+	case ctx.IsSelfDrawn():
+		add("Self drawn", 1)
+	case allMelded:
+		add("All melded", 2)
+	}
+
+	if wait == WaitPair || wait == WaitEdge || wait == WaitClosed {
+		add("Single wait", 1)
+	}
+
+	suits := handSuits(d)
+	if len(pungs) == 0 && len(ctx.BonusTiles) == 0 && !d.Pair.IsHonor() && wait == WaitOpen && !ctx.IsSelfDrawn() {
+		add("All chows", 2)
+	}
+	if chows == 0 {
+		add("All pungs", 4)
+	}
+
+	switch concealedPungs {
+	case 5:
+		add("Five concealed pungs", 8)
+	case 4:
+		add("Four concealed pungs", 5)
+	case 3:
+		add("Three concealed pungs", 2)
+	}
+
+	dragons := 0
+	winds := 0
+	for _, pung := range pungs {
+		if pung.IsDragon() {
+			dragons++
+		}
+		if pung.IsWind() {
+			winds++
+		}
+	}
+
+	switch {
+	case dragons == 3:
+		add("Big three dragons", 8)
+	case dragons == 2 && d.Pair.IsDragon():
+		add("Little three dragons", 4)
+	default:
+		for i := 0; i < dragons; i++ {
+			add("Pung of dragons", 1)
+		}
+	}
+
+	switch {
+	case winds == 4:
+		add("Big four winds", 16)
+	case winds == 3 && d.Pair.IsWind():
+		add("Little four winds", 8)
+	default:
+		for _, pung := range pungs {
+			if pung == ctx.SeatWind.Tile() {
+				add("Pung of seat wind", 1)
+			}
+			if pung == ctx.PrevalentWind.Tile() {
+				add("Pung of prevalent wind", 1)
+			}
+		}
+	}
+
+	switch suits {
+	case suitsAllHonors:
+		add("All honors", 16)
+	case suitsPure:
+		add("Full flush", 8)
+	case suitsMixed:
+		add("Half flush", 4)
+	}
+
+	if ctx.LastTile {
+		add("Winning on the last tile", 1)
+	}
+	if ctx.ReplacementTile {
+		add("Winning on a replacement tile", 1)
+	}
+	if ctx.RobbingKong {
+		add("Robbing the kong", 1)
+	}
+
+	if len(ctx.BonusTiles) == 8 {
+		add("All flowers and seasons", 8)
+	} else {
+		items = append(items, seatBonusTileItems(ctx)...)
+	}
+
+	tai := 0
+	for _, item := range items {
+		tai += item.Doubles
+	}
+
+	return Score{
+		Items: items,
+		Value: tai,
+		Total: s.Base + tai*s.PerTai,
+	}
+}
+
+// The discarder pays the hand value, when self drawn every other player pays it.
+// A dealer that pays also pays a tai for being dealer and two for every repeat, a winning dealer already has them in the hand value.
+func (s TaiwaneseScorer) Payments(score Score, ctx WinContext) map[int]int {
+	payments := make(map[int]int, len(ctx.Seats))
+
+	for _, seat := range ctx.Seats {
+		if seat == ctx.Winner || (!ctx.IsSelfDrawn() && seat != ctx.Discarder) {
+			continue
+		}
+		amount := score.Total
+		if seat == ctx.Dealer {
+			amount += (1 + 2*ctx.Repeats) * s.PerTai
+		}
+		payments[seat] -= amount
+		payments[ctx.Winner] += amount
+	}
+
+	return payments
+}
@@ -0,0 +1,154 @@
+package mahjong
+
+// Scorer values winning hands and determines how the points are paid.
+type Scorer interface {
+	// Score the winning hand. The concealed tiles of the hand include the winning tile.
+	Score(hand WinningHand, ctx WinContext) Score
+
+	// The change in score per seat as a result of the scored win. The changes should add up to zero.
+	Payments(score Score, ctx WinContext) map[int]int
+}
+
+// WinRequirement is implemented by scorers that do not accept every complete hand as a win.
+type WinRequirement interface {
+	// Whether the hand may be declared as a win.
+	AllowsWin(hand WinningHand, ctx WinContext) bool
+}
+
+// DrawSettler is implemented by scorers that let players pay each other when the wall is exhausted.
+type DrawSettler interface {
+	// The change in score per seat, given the seats that were one tile away from winning. The changes should add up to zero.
+	DrawPayments(tenpai []int, seats []int) map[int]int
+}
+
+// WinningHand holds the tiles of a player at the moment of declaring mahjong.
+type WinningHand struct {
+	Concealed *TileCollection
+	Exposed   *CombinationCollection
+	// The readings of the hand that the ruleset accepts as a win.
+	Decompositions []Decomposition
+}
+
+// WinContext holds the circumstances of a win that cannot be read from the hand itself.
+type WinContext struct {
+	Winner int
+	// Seat that discarded the winning tile, or -1 if the tile was drawn from the wall.
+	Discarder     int
+	WinningTile   Tile
+	SeatWind      Wind
+	PrevalentWind Wind
+	BonusTiles    []Tile
+	// Whether the winning tile was drawn as a replacement after declaring a kong or bonus tile.
+	ReplacementTile bool
+	// Whether the winning tile was taken from another player adding it to an exposed pung.
+	RobbingKong bool
+	// Whether the winning tile was the last tile of the live wall, or the discard following it.
+	LastTile bool
+	// Whether the other three tiles of the kind of the winning tile were already visible in discards or exposed sets.
+	LastOfKind bool
+	// Seat of the player that is East in this round.
+	Dealer int
+	// All seats taking part in the round, including the winner.
+	Seats []int
+	// Number of hands in a row the dealer kept the seat or that ended in a draw.
+	Repeats int
+	// Whether the winner declared riichi, and whether they won within a go-around after declaring without any claims in between.
+	Riichi  RiichiStatus
+	Ippatsu bool
+	// Whether the winning tile was the first tile drawn by the winner, without any claims before it.
+	FirstDraw bool
+	// Tiles that count as dora, once for every indicator pointing at them. Ura dora only count if the winner declared riichi.
+	Dora    []Tile
+	UraDora []Tile
+	// Number of red fives in the hand, including the winning tile.
+	RedFives int
+}
+
+func (c WinContext) IsSelfDrawn() bool {
+	return c.Discarder == -1
+}
+
+// Score is the value of a winning hand along with how it was composed.
+type Score struct {
+	Items []ScoreItem
+	// Headline value of the hand in the unit of the scoring system, such as fan or han.
+	Value int
+	// Amount that payments are based on.
+	Total int
+}
+
+// ScoreItem is a single scoring element of a hand.
+type ScoreItem struct {
+	Name    string
+	Points  int
+	Doubles int
+}
+
+// RoundResult describes how the previous round ended.
+type RoundResult struct {
+	// Wins in the round, empty if the round ended in a draw.
+	Wins []Win
+	// Whether the round was drawn before the wall was exhausted, in which case the dealer always keeps the seat.
+	Abortive bool
+	// Seats that were waiting for a single tile to complete their hand when the round ended in a draw.
+	Tenpai []int
+	// Payments made because the round ended in a draw, nil if the ruleset has none.
+	Payments map[int]int
+}
+
+// Win is a declared mahjong, which is scored and settled at the end of the round.
+type Win struct {
+	Context  WinContext
+	Hand     WinningHand
+	Score    Score
+	Payments map[int]int
+	// Riichi deposits collected by the winner.
+	Deposits int
+}
+
+// Doubles for the flower and season of the winner's seat, and two for every complete set of four flowers or seasons.
+// A complete set replaces the own flower or season in it.
+func seatBonusTileItems(ctx WinContext) []ScoreItem {
+	flowers := 0
+	seasons := 0
+	for _, bonus := range ctx.BonusTiles {
+		if bonus < SeasonSpring {
+			flowers++
+		} else {
+			seasons++
+		}
+	}
+
+	items := make([]ScoreItem, 0)
+	for _, bonus := range ctx.BonusTiles {
+		switch bonus {
+		case FlowerPlumb + Tile(ctx.SeatWind):
+			if flowers < 4 {
+				items = append(items, ScoreItem{Name: "Own flower", Doubles: 1})
+			}
+		case SeasonSpring + Tile(ctx.SeatWind):
+			if seasons < 4 {
+				items = append(items, ScoreItem{Name: "Own season", Doubles: 1})
+			}
+		}
+	}
+	if flowers == 4 {
+		items = append(items, ScoreItem{Name: "Set of flowers", Doubles: 2})
+	}
+	if seasons == 4 {
+		items = append(items, ScoreItem{Name: "Set of seasons", Doubles: 2})
+	}
+	return items
+}
+
+// The best scoring reading of a hand, given a score per decomposition.
+func bestScore(decompositions []Decomposition, score func(d Decomposition) Score) Score {
+	var best Score
+	for i, d := range decompositions {
+		s := score(d)
+		if i == 0 || s.Total > best.Total {
+			best = s
+		}
+	}
+	return best
+}
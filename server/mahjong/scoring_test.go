@@ -0,0 +1,379 @@
+package mahjong
+
+import "testing"
+
+func TestClassicalScorer(t *testing.T) {
+	exposed := newCombinationCollection()
+	exposed.add(Pung{Tile: Circles5})
+	hand := WinningHand{
+		Concealed: tilesOf(RedDragon, RedDragon, RedDragon, Bamboo1, Bamboo2, Bamboo3, Bamboo4, Bamboo5, Bamboo6, EastWind, EastWind),
+		Exposed:   exposed,
+	}
+	hand.Decompositions = Decompose(hand.Concealed, hand.Exposed)
+	ctx := WinContext{
+		Winner:        0,
+		Discarder:     -1,
+		WinningTile:   EastWind,
+		SeatWind:      East,
+		PrevalentWind: East,
+		Dealer:        0,
+		Seats:         []int{0, 1, 2, 3},
+	}
+
+	scorer := ClassicalScorer{Limit: 1000}
+	score := scorer.Score(hand, ctx)
+	if score.Total != 76 {
+		t.Errorf("expected a score of [76], got [%d]: %+v", score.Total, score.Items)
+	}
+
+	payments := scorer.Payments(score, ctx)
+	if payments[0] != 456 || payments[1] != -152 || payments[2] != -152 || payments[3] != -152 {
+		t.Errorf("expected the dealer to receive double from everyone, got %+v", payments)
+	}
+}
+
+func TestClassicalScorerLimitHands(t *testing.T) {
+	ctx := WinContext{Winner: 1, Discarder: 2, Dealer: 0, Seats: []int{0, 1, 2, 3}}
+	scorer := ClassicalScorer{Limit: 500, NineGates: true}
+
+	orphans := tilesOf(Bamboo1, Bamboo9, Circles1, Circles9, Characters1, Characters9, RedDragon, GreenDragon, WhiteDragon, EastWind, SouthWind, WestWind, NorthWind, NorthWind)
+	hand := WinningHand{Concealed: orphans, Decompositions: Decompose(orphans, nil)}
+	if score := scorer.Score(hand, ctx); score.Total != 500 {
+		t.Errorf("expected thirteen orphans to score the limit, got [%d]: %+v", score.Total, score.Items)
+	}
+
+	gates := tilesOf(Circles1, Circles1, Circles1, Circles2, Circles3, Circles4, Circles5, Circles5, Circles6, Circles7, Circles8, Circles9, Circles9, Circles9)
+	hand = WinningHand{Concealed: gates, Decompositions: Decompose(gates, nil)}
+	if score := scorer.Score(hand, ctx); score.Total != 500 {
+		t.Errorf("expected nine gates to score the limit, got [%d]: %+v", score.Total, score.Items)
+	}
+
+	scorer.NineGates = false
+	if score := scorer.Score(hand, ctx); score.Total == 500 {
+		t.Errorf("expected nine gates to be scored as a regular hand when disabled")
+	}
+}
+
+func TestMCRScorer(t *testing.T) {
+	concealed := tilesOf(Bamboo1, Bamboo2, Bamboo3, Bamboo4, Bamboo5, Bamboo6, Bamboo7, Bamboo8, Bamboo9, Circles2, Circles3, Circles4, Characters5, Characters5)
+	hand := WinningHand{Concealed: concealed, Exposed: newCombinationCollection()}
+	hand.Decompositions = MCRRuleset().decompose(hand.Concealed, hand.Exposed)
+	ctx := WinContext{
+		Winner:        1,
+		Discarder:     2,
+		WinningTile:   Bamboo5,
+		SeatWind:      South,
+		PrevalentWind: East,
+		Dealer:        0,
+		Seats:         []int{0, 1, 2, 3},
+	}
+
+	scorer := MCRScorer{MinimumPoints: 8, BasePayment: 8}
+	score := scorer.Score(hand, ctx)
+	// pure straight, concealed hand, all chows and closed wait, the short straights within the pure straight are not counted
+	if score.Total != 21 {
+		t.Errorf("expected a total of [21], got [%d]: %+v", score.Total, score.Items)
+	}
+
+	payments := scorer.Payments(score, ctx)
+	if payments[1] != 45 || payments[2] != -29 || payments[0] != -8 || payments[3] != -8 {
+		t.Errorf("expected the discarder to pay the hand value on top of the base payment, got %+v", payments)
+	}
+}
+
+func TestMCRScorerCombinesSetsOnce(t *testing.T) {
+	concealed := tilesOf(Circles1, Circles2, Circles3, Circles1, Circles2, Circles3, Circles4, Circles5, Circles6, Circles4, Circles5, Circles6, RedDragon, RedDragon)
+	hand := WinningHand{Concealed: concealed, Exposed: newCombinationCollection()}
+	hand.Decompositions = []Decomposition{{
+		Form:      FormRegular,
+		Pair:      RedDragon,
+		Concealed: []Combination{Chow{FirstTile: Circles1}, Chow{FirstTile: Circles1}, Chow{FirstTile: Circles4}, Chow{FirstTile: Circles4}},
+		Exposed:   []Combination{},
+	}}
+	ctx := WinContext{Discarder: 2, WinningTile: RedDragon, SeatWind: South, PrevalentWind: East}
+
+	fans := make(map[string]int)
+	for _, item := range (MCRScorer{}).Score(hand, ctx).Items {
+		fans[item.Name]++
+	}
+	// four chows can be combined into at most three fan
+	if fans["Pure double chow"] != 2 || fans["Short straight"] != 1 {
+		t.Errorf("expected two pure double chows and a single short straight, got %+v", fans)
+	}
+}
+
+func TestMCRScorerMinimumPoints(t *testing.T) {
+	exposed := newCombinationCollection()
+	exposed.add(Chow{FirstTile: Characters4})
+	concealed := tilesOf(Bamboo1, Bamboo2, Bamboo3, Bamboo6, Bamboo7, Bamboo8, Circles2, Circles3, Circles4, Characters9, Characters9)
+	hand := WinningHand{Concealed: concealed, Exposed: exposed}
+	hand.Decompositions = MCRRuleset().decompose(hand.Concealed, hand.Exposed)
+	ctx := WinContext{
+		Discarder:     2,
+		WinningTile:   Circles4,
+		SeatWind:      South,
+		PrevalentWind: East,
+		BonusTiles:    []Tile{FlowerPlumb, FlowerOrchid, SeasonSpring, SeasonSummer, SeasonAutumn, SeasonWinter},
+	}
+
+	scorer := MCRScorer{MinimumPoints: 8, BasePayment: 8}
+	score := scorer.Score(hand, ctx)
+	// all chows, with the flowers on top
+	if score.Value != 2 || score.Total != 8 {
+		t.Errorf("expected a value of [2] and a total of [8], got [%d] and [%d]: %+v", score.Value, score.Total, score.Items)
+	}
+	if scorer.AllowsWin(hand, ctx) {
+		t.Errorf("expected flowers not to count towards the minimum")
+	}
+}
+
+func TestHongKongScorer(t *testing.T) {
+	exposed := newCombinationCollection()
+	exposed.add(Pung{Tile: EastWind})
+	concealed := tilesOf(RedDragon, RedDragon, RedDragon, Bamboo2, Bamboo3, Bamboo4, Bamboo6, Bamboo7, Bamboo8, Bamboo5, Bamboo5)
+	hand := WinningHand{Concealed: concealed, Exposed: exposed}
+	hand.Decompositions = HongKongRuleset().decompose(hand.Concealed, hand.Exposed)
+	ctx := WinContext{
+		Winner:        1,
+		Discarder:     2,
+		WinningTile:   Bamboo5,
+		SeatWind:      South,
+		PrevalentWind: East,
+		BonusTiles:    []Tile{FlowerOrchid},
+		Dealer:        0,
+		Seats:         []int{0, 1, 2, 3},
+	}
+
+	scorer := HongKongScorer{MinimumFaan: 3, LimitFaan: 10}
+	score := scorer.Score(hand, ctx)
+	// half flush, pung of dragons, pung of prevalent wind and own flower
+	if score.Value != 6 || score.Total != 32 {
+		t.Errorf("expected [6] faan worth [32] points, got [%d] and [%d]: %+v", score.Value, score.Total, score.Items)
+	}
+
+	payments := scorer.Payments(score, ctx)
+	if payments[1] != 128 || payments[2] != -64 || payments[0] != -32 || payments[3] != -32 {
+		t.Errorf("expected the discarder to pay double, got %+v", payments)
+	}
+
+	ctx.BonusTiles = []Tile{FlowerPlumb, FlowerOrchid, FlowerChrysanthemum, FlowerBamboo, SeasonSummer}
+	items := scorer.bonusTileItems(ctx)
+	if len(items) != 2 || items[0].Name != "Own season" || items[1].Name != "Set of flowers" {
+		t.Errorf("expected a set of flowers to replace the own flower, got %+v", items)
+	}
+
+	ctx.BonusTiles = nil
+	hand.Exposed = newCombinationCollection()
+	hand.Exposed.add(Chow{FirstTile: Circles1})
+	hand.Decompositions = HongKongRuleset().decompose(hand.Concealed, hand.Exposed)
+	// pung of dragons and no flowers
+	if score := scorer.Score(hand, ctx); score.Value != 2 || scorer.AllowsWin(hand, ctx) {
+		t.Errorf("expected [2] faan to be below the minimum, got [%d]: %+v", score.Value, score.Items)
+	}
+}
+
+func TestTaiwaneseScorer(t *testing.T) {
+	exposed := newCombinationCollection()
+	exposed.add(Pung{Tile: RedDragon})
+	concealed := tilesOf(Bamboo1, Bamboo2, Bamboo3, Bamboo4, Bamboo5, Bamboo6, Circles7, Circles8, Circles9, Characters2, Characters3, Characters4, EastWind, EastWind)
+	hand := WinningHand{Concealed: concealed, Exposed: exposed}
+	hand.Decompositions = TaiwaneseRuleset().decompose(hand.Concealed, hand.Exposed)
+	ctx := WinContext{
+		Winner:        1,
+		Discarder:     0,
+		WinningTile:   Characters2,
+		SeatWind:      South,
+		PrevalentWind: East,
+		Dealer:        0,
+		Seats:         []int{0, 1, 2, 3},
+		Repeats:       1,
+	}
+
+	scorer := TaiwaneseScorer{Base: 300, PerTai: 100}
+	score := scorer.Score(hand, ctx)
+	// pung of dragons
+	if score.Value != 1 || score.Total != 400 {
+		t.Errorf("expected [1] tai worth [400], got [%d] and [%d]: %+v", score.Value, score.Total, score.Items)
+	}
+	if payments := scorer.Payments(score, ctx); payments[0] != -700 || payments[1] != 700 || payments[2] != 0 {
+		t.Errorf("expected only the discarding dealer to pay, including the dealer tai, got %+v", payments)
+	}
+
+	hand.Concealed.add(RedDragon)
+	hand.Concealed.add(RedDragon)
+	hand.Concealed.add(RedDragon)
+	hand.Exposed = newCombinationCollection()
+	hand.Decompositions = TaiwaneseRuleset().decompose(hand.Concealed, hand.Exposed)
+	ctx.Winner = 0
+	ctx.Discarder = -1
+	ctx.SeatWind = East
+	score = scorer.Score(hand, ctx)
+	// dealer, one repeat, concealed self drawn and pung of dragons
+	if score.Value != 7 || score.Total != 1000 {
+		t.Errorf("expected [7] tai worth [1000], got [%d] and [%d]: %+v", score.Value, score.Total, score.Items)
+	}
+	if payments := scorer.Payments(score, ctx); payments[0] != 3000 || payments[1] != -1000 {
+		t.Errorf("expected every other player to pay when self drawn, got %+v", payments)
+	}
+}
+
+func TestSichuanScorer(t *testing.T) {
+	exposed := newCombinationCollection()
+	exposed.add(Kong{Tile: Bamboo9})
+	concealed := tilesOf(Bamboo1, Bamboo1, Bamboo1, Bamboo3, Bamboo3, Bamboo3, Bamboo5, Bamboo5, Bamboo5, Bamboo7, Bamboo7)
+	hand := WinningHand{Concealed: concealed, Exposed: exposed}
+	hand.Decompositions = SichuanRuleset().decompose(hand.Concealed, hand.Exposed)
+	ctx := WinContext{
+		Winner:      1,
+		Discarder:   3,
+		WinningTile: Bamboo7,
+		Dealer:      0,
+		Seats:       []int{0, 1, 3},
+	}
+
+	scorer := SichuanScorer{Base: 1, LimitFan: 4}
+	score := scorer.Score(hand, ctx)
+	// all pungs, full flush and the kong as root
+	if score.Value != 4 || score.Total != 16 {
+		t.Errorf("expected [4] fan worth [16], got [%d] and [%d]: %+v", score.Value, score.Total, score.Items)
+	}
+	if payments := scorer.Payments(score, ctx); payments[3] != -16 || payments[1] != 16 || payments[0] != 0 {
+		t.Errorf("expected only the discarder to pay, got %+v", payments)
+	}
+
+	ctx.Discarder = -1
+	score = scorer.Score(hand, ctx)
+	if score.Value != 4 {
+		t.Errorf("expected the fan to be limited to [4], got [%d]", score.Value)
+	}
+	if payments := scorer.Payments(score, ctx); payments[1] != 32 || payments[2] != 0 {
+		t.Errorf("expected only the players still in the hand to pay when self drawn, got %+v", payments)
+	}
+
+	if payments := scorer.DrawPayments([]int{0}, []int{0, 2, 3}); payments[0] != 2 || payments[2] != -1 || payments[3] != -1 {
+		t.Errorf("expected the players without a ready hand to pay the ones with, got %+v", payments)
+	}
+}
+
+func TestAmericanScorer(t *testing.T) {
+	card, err := LoadCard("cards/sample.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	hand := WinningHand{
+		Concealed: tilesOf(Bamboo2, Bamboo2, Bamboo2, Bamboo4, Bamboo4, Bamboo4, Bamboo4, Bamboo6, Bamboo6, Bamboo6, Bamboo8, Bamboo8, Bamboo8, Bamboo8),
+		Exposed:   newCombinationCollection(),
+	}
+	ctx := WinContext{Winner: 1, Discarder: 2, WinningTile: Bamboo8, Seats: []int{0, 1, 2, 3}}
+
+	scorer := AmericanScorer{Card: card}
+	score := scorer.Score(hand, ctx)
+	if score.Value != 25 || score.Total != 50 {
+		t.Errorf("expected a jokerless hand worth [25] to be doubled to [50], got [%d] and [%d]: %+v", score.Value, score.Total, score.Items)
+	}
+	if payments := scorer.Payments(score, ctx); payments[2] != -100 || payments[0] != -50 || payments[1] != 200 {
+		t.Errorf("expected the discarder to pay double, got %+v", payments)
+	}
+
+	hand.Concealed.remove(Bamboo8)
+	hand.Concealed.add(Joker)
+	if score := scorer.Score(hand, ctx); score.Total != 25 {
+		t.Errorf("expected a hand with a joker not to be doubled, got [%d]", score.Total)
+	}
+}
+
+func TestRiichiScorer(t *testing.T) {
+	concealed := tilesOf(Characters2, Characters3, Characters4, Circles5, Circles6, Circles7, Bamboo3, Bamboo4, Bamboo5, Bamboo6, Bamboo7, Bamboo8, Circles2, Circles2)
+	hand := WinningHand{Concealed: concealed, Exposed: newCombinationCollection()}
+	hand.Decompositions = RiichiRuleset().decompose(hand.Concealed, hand.Exposed)
+	ctx := WinContext{
+		Winner:        1,
+		Discarder:     -1,
+		WinningTile:   Bamboo3,
+		SeatWind:      South,
+		PrevalentWind: East,
+		Dealer:        0,
+		Seats:         []int{0, 1, 2, 3},
+		Riichi:        RiichiDeclared,
+	}
+
+	scorer := RiichiScorer{}
+	score := scorer.Score(hand, ctx)
+	// riichi, fully concealed hand, pinfu and all simples at 20 fu
+	if score.Value != 4 || score.Total != 1280 {
+		t.Errorf("expected [4] han and [1280] base points, got [%d] and [%d]: %+v", score.Value, score.Total, score.Items)
+	}
+
+	payments := scorer.Payments(score, ctx)
+	if payments[0] != -2600 || payments[1] != 5200 || payments[2] != -1300 || payments[3] != -1300 {
+		t.Errorf("expected the dealer to pay double, rounded up to 100, got %+v", payments)
+	}
+
+	ctx.Discarder = 2
+	ctx.Riichi = RiichiNone
+	ctx.Dora = []Tile{Bamboo4}
+	score = scorer.Score(hand, ctx)
+	// pinfu, all simples and one dora at 30 fu
+	if score.Value != 3 || score.Total != 960 {
+		t.Errorf("expected [3] han and [960] base points, got [%d] and [%d]: %+v", score.Value, score.Total, score.Items)
+	}
+	if payments := scorer.Payments(score, ctx); payments[2] != -3900 || payments[1] != 3900 {
+		t.Errorf("expected the discarder to pay four times the base points, got %+v", payments)
+	}
+}
+
+func TestRiichiScorerRequiresYaku(t *testing.T) {
+	exposed := newCombinationCollection()
+	exposed.add(Chow{FirstTile: Bamboo1})
+	concealed := tilesOf(Circles2, Circles3, Circles4, Characters6, Characters7, Characters8, Circles7, Circles8, Circles9, EastWind, EastWind)
+	hand := WinningHand{Concealed: concealed, Exposed: exposed}
+	hand.Decompositions = RiichiRuleset().decompose(hand.Concealed, hand.Exposed)
+	ctx := WinContext{Winner: 1, Discarder: 0, WinningTile: Circles9, SeatWind: South, PrevalentWind: East, Dora: []Tile{EastWind}, Seats: []int{0, 1, 2, 3}}
+
+	if (RiichiScorer{}).AllowsWin(hand, ctx) {
+		t.Errorf("expected an open hand without yaku not to win, even with dora")
+	}
+
+	ctx.LastTile = true
+	if !(RiichiScorer{}).AllowsWin(hand, ctx) {
+		t.Errorf("expected winning on the last discard to count as a yaku")
+	}
+}
+
+func TestRiichiDrawPayments(t *testing.T) {
+	payments := RiichiScorer{}.DrawPayments([]int{0, 2}, []int{0, 1, 2, 3})
+	if payments[0] != 1500 || payments[1] != -1500 || payments[2] != 1500 || payments[3] != -1500 {
+		t.Errorf("expected the noten players to pay the tenpai players, got %+v", payments)
+	}
+}
+
+func TestDoraFromIndicator(t *testing.T) {
+	cases := map[Tile]Tile{
+		Bamboo3:     Bamboo4,
+		Circles9:    Circles1,
+		NorthWind:   EastWind,
+		SouthWind:   WestWind,
+		WhiteDragon: GreenDragon,
+		RedDragon:   WhiteDragon,
+	}
+	for indicator, dora := range cases {
+		if indicator.DoraFromIndicator() != dora {
+			t.Errorf("expected indicator [%d] to point at [%d], got [%d]", indicator, dora, indicator.DoraFromIndicator())
+		}
+	}
+}
+
+func TestDoraFromIndicatorSkipsExcludedTiles(t *testing.T) {
+	tiles := SanmaRuleset().Tiles
+	cases := map[Tile]Tile{
+		Characters1: Characters9,
+		Characters9: Characters1,
+		Circles9:    Circles1,
+	}
+	for indicator, dora := range cases {
+		if tiles.doraFromIndicator(indicator) != dora {
+			t.Errorf("expected indicator [%d] to point at [%d], got [%d]", indicator, dora, tiles.doraFromIndicator(indicator))
+		}
+	}
+}
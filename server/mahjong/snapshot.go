@@ -0,0 +1,657 @@
+package mahjong
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/roelofruis/mahjong-learn/state"
+	"strconv"
+)
+
+// Version of the snapshot format, raised whenever a snapshot of an older version can no longer be restored.
+const snapshotVersion = 1
+
+// Snapshot is the complete state of a running game, from which the game can be restored to go on exactly where it was.
+type Snapshot struct {
+	Version int `json:"version"`
+	// The log of the game up to the snapshot, which also gives the ruleset and its options.
+	Log GameLog `json:"log"`
+	// Name of the state the game is in, which is rebuilt from the table.
+	State string        `json:"state"`
+	Table tableSnapshot `json:"table"`
+}
+
+type tableSnapshot struct {
+	PrevalentWind Wind `json:"prevalent_wind"`
+	Dealer        int  `json:"dealer"`
+	HandsPlayed   int  `json:"hands_played"`
+	Repeats       int  `json:"repeats"`
+	// State of the random source, which shuffles the walls of the following hands.
+	Random        uint64           `json:"random"`
+	Wall          wallSnapshot     `json:"wall"`
+	ActiveDiscard *Tile            `json:"active_discard"`
+	Players       []playerSnapshot `json:"players"`
+	ActivePlayer  int              `json:"active_player"`
+
+	ReceivedReplacement bool  `json:"received_replacement"`
+	AddedKongTile       *Tile `json:"added_kong_tile"`
+	ActiveDiscardRed    bool  `json:"active_discard_red"`
+	AddedKongRed        bool  `json:"added_kong_red"`
+	RiichiDiscard       bool  `json:"riichi_discard"`
+	Interrupted         bool  `json:"interrupted"`
+	RevealedIndicators  int   `json:"revealed_indicators"`
+	Deposits            int   `json:"deposits"`
+	CharlestonPasses    int   `json:"charleston_passes"`
+
+	Wins      []Win        `json:"wins"`
+	Abortive  bool         `json:"abortive"`
+	LastRound *RoundResult `json:"last_round"`
+}
+
+type wallSnapshot struct {
+	Live       []wallTileSnapshot `json:"live"`
+	Dead       []wallTileSnapshot `json:"dead"`
+	Indicators []wallTileSnapshot `json:"indicators"`
+}
+
+type wallTileSnapshot struct {
+	Tile Tile `json:"tile"`
+	Red  bool `json:"red,omitempty"`
+}
+
+type playerSnapshot struct {
+	Score           int                    `json:"score"`
+	Received        *Tile                  `json:"received"`
+	Wind            Wind                   `json:"wind"`
+	Concealed       *TileCollection        `json:"concealed"`
+	Exposed         *CombinationCollection `json:"exposed"`
+	Discarded       *TileCollection        `json:"discarded"`
+	Discards        []DiscardedTile        `json:"discards"`
+	PassedOnWin     bool                   `json:"passed_on_win"`
+	Riichi          RiichiStatus           `json:"riichi"`
+	Ippatsu         bool                   `json:"ippatsu"`
+	RedFives        map[Tile]int           `json:"red_fives"`
+	ExposedRedFives int                    `json:"exposed_red_fives"`
+	MissingSuit     Tile                   `json:"missing_suit"`
+	Won             bool                   `json:"won"`
+}
+
+// Take a snapshot of the game as it is now.
+func (g *Game) Snapshot() Snapshot {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return Snapshot{
+		Version: snapshotVersion,
+		Log:     g.log.copy(),
+		State:   g.StateMachine.StateName(),
+		Table:   g.Table.snapshot(),
+	}
+}
+
+// Restore rebuilds a game from a snapshot. The ruleset has to be registered under the name in the log of the snapshot.
+func Restore(snapshot Snapshot) (*Game, error) {
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version [%d], expected [%d]", snapshot.Version, snapshotVersion)
+	}
+
+	rules, err := snapshot.Log.rules()
+	if err != nil {
+		return nil, err
+	}
+
+	err = snapshot.Table.validate(rules, snapshot.State)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %s", err.Error())
+	}
+
+	table := newTable(rules, snapshot.Log.Seed)
+	table.restore(snapshot.Table)
+
+	current, err := table.states.restore(snapshot.State, table)
+	if err != nil {
+		return nil, err
+	}
+
+	log := snapshot.Log.copy()
+
+	return &Game{
+		Table:        table,
+		StateMachine: state.NewStateMachine(current, &state.ProductionTransitioner{IntermediateTransitionLimit: 10}),
+		log:          &log,
+	}, nil
+}
+
+// Rebuild the state with the given name from the table. Only states in which the game waits for actions,
+// or has ended, can be rebuilt.
+func (s stateSet) restore(name string, table *Table) (*state.State, error) {
+	generators := map[string]stateGenerator{
+		"Must Discard":   s.mustDiscard,
+		"Tile Discarded": s.tileDiscarded,
+		"Kong Added":     s.kongAdded,
+		"Game Ended":     s.gameEnded,
+	}
+	if generator, has := generators[name]; has {
+		return generator(table), nil
+	}
+
+	// The state a hand starts in differs per ruleset, it is only known by name once generated.
+	started := s.handDealt(table)
+	if started.Name() == name {
+		return started, nil
+	}
+	return nil, fmt.Errorf("state [%s] cannot be restored", name)
+}
+
+func (t *Table) snapshot() tableSnapshot {
+	players := make([]playerSnapshot, len(t.players))
+	for _, s := range t.seats() {
+		players[s] = t.players[s].snapshot()
+	}
+
+	return tableSnapshot{
+		PrevalentWind: t.prevalentWind,
+		Dealer:        t.dealer,
+		HandsPlayed:   t.handsPlayed,
+		Repeats:       t.repeats,
+		Random:        t.random.state,
+		Wall:          t.wall.snapshot(),
+		ActiveDiscard: copyTile(t.activeDiscard),
+		Players:       players,
+		ActivePlayer:  t.activePlayer,
+
+		ReceivedReplacement: t.receivedReplacement,
+		AddedKongTile:       copyTile(t.addedKongTile),
+		ActiveDiscardRed:    t.activeDiscardRed,
+		AddedKongRed:        t.addedKongRed,
+		RiichiDiscard:       t.riichiDiscard,
+		Interrupted:         t.interrupted,
+		RevealedIndicators:  t.revealedIndicators,
+		Deposits:            t.deposits,
+		CharlestonPasses:    t.charlestonPasses,
+
+		Wins:      append([]Win(nil), t.wins...),
+		Abortive:  t.abortive,
+		LastRound: t.lastRound,
+	}
+}
+
+func (t *Table) restore(s tableSnapshot) {
+	t.setRandomSource(&randomSource{state: s.Random})
+	t.prevalentWind = s.PrevalentWind
+	t.dealer = s.Dealer
+	t.handsPlayed = s.HandsPlayed
+	t.repeats = s.Repeats
+	t.wall = s.Wall.restore()
+	t.activeDiscard = s.ActiveDiscard
+	for seat, p := range s.Players {
+		t.players[seat] = p.restore()
+	}
+	t.activePlayer = s.ActivePlayer
+
+	t.receivedReplacement = s.ReceivedReplacement
+	t.addedKongTile = s.AddedKongTile
+	t.activeDiscardRed = s.ActiveDiscardRed
+	t.addedKongRed = s.AddedKongRed
+	t.riichiDiscard = s.RiichiDiscard
+	t.interrupted = s.Interrupted
+	t.revealedIndicators = s.RevealedIndicators
+	t.deposits = s.Deposits
+	t.charlestonPasses = s.CharlestonPasses
+
+	t.wins = s.Wins
+	t.abortive = s.Abortive
+	t.lastRound = s.LastRound
+}
+
+// Check the snapshot against the ruleset, so that a game is never restored onto a table it cannot be played on.
+func (s tableSnapshot) validate(rules Ruleset, stateName string) error {
+	players := rules.Players
+	if len(s.Players) != players {
+		return fmt.Errorf("[%d] players, the ruleset has [%d]", len(s.Players), players)
+	}
+	if !isSeat(s.Dealer, players) {
+		return fmt.Errorf("dealer [%d] is not a seat", s.Dealer)
+	}
+	if !isSeat(s.ActivePlayer, players) {
+		return fmt.Errorf("active player [%d] is not a seat", s.ActivePlayer)
+	}
+	if s.PrevalentWind < East || s.PrevalentWind > North {
+		return fmt.Errorf("unknown prevalent wind [%d]", s.PrevalentWind)
+	}
+	if s.RevealedIndicators < 0 || s.RevealedIndicators > rules.DoraIndicators || s.RevealedIndicators > len(s.Wall.Indicators) {
+		return fmt.Errorf("[%d] revealed indicators out of range", s.RevealedIndicators)
+	}
+
+	set := rules.tileSet().collection()
+	for _, part := range [][]wallTileSnapshot{s.Wall.Live, s.Wall.Dead, s.Wall.Indicators} {
+		for _, w := range part {
+			if err := checkTiles(set, w.Tile); err != nil {
+				return err
+			}
+		}
+	}
+	if err := checkTiles(set, optionalTiles(s.ActiveDiscard, s.AddedKongTile)...); err != nil {
+		return err
+	}
+
+	switch {
+	case stateName == "Tile Discarded" && s.ActiveDiscard == nil:
+		return fmt.Errorf("state [%s] without an active discard", stateName)
+	case stateName == "Kong Added" && s.AddedKongTile == nil:
+		return fmt.Errorf("state [%s] without an added kong tile", stateName)
+	}
+
+	for seat, p := range s.Players {
+		if err := p.validate(set, seat == s.ActivePlayer, players); err != nil {
+			return fmt.Errorf("player [%d]: %s", seat, err.Error())
+		}
+	}
+
+	if err := s.checkAllTiles(set); err != nil {
+		return err
+	}
+
+	for _, w := range s.Wins {
+		if !isSeat(w.Context.Winner, players) || (w.Context.Discarder != -1 && !isSeat(w.Context.Discarder, players)) {
+			return fmt.Errorf("win of [%d] on a tile of [%d] is not between seats", w.Context.Winner, w.Context.Discarder)
+		}
+		if w.Hand.Concealed == nil || w.Hand.Exposed == nil {
+			return fmt.Errorf("win of [%d] without a hand", w.Context.Winner)
+		}
+		if err := checkTiles(set, w.Context.WinningTile); err != nil {
+			return err
+		}
+		if err := checkCollection(set, w.Hand.Concealed); err != nil {
+			return err
+		}
+		if err := checkCombinations(set, w.Hand.Exposed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// All tiles on the table together, in the wall, in the hands, in the sets and in the discards, have to make up the tile set.
+func (s tableSnapshot) checkAllTiles(set *TileCollection) error {
+	tiles := newEmptyTileCollection()
+	for _, part := range [][]wallTileSnapshot{s.Wall.Live, s.Wall.Dead, s.Wall.Indicators} {
+		for _, w := range part {
+			tiles.add(w.Tile)
+		}
+	}
+	for _, tile := range optionalTiles(s.ActiveDiscard, s.AddedKongTile) {
+		tiles.add(tile)
+	}
+	for _, p := range s.Players {
+		for _, tile := range optionalTiles(p.Received) {
+			tiles.add(tile)
+		}
+		for _, c := range []*TileCollection{p.Concealed, p.Discarded} {
+			if c == nil {
+				continue
+			}
+			for tile, count := range c.tiles {
+				for i := uint8(0); i < count; i++ {
+					tiles.add(tile)
+				}
+			}
+		}
+		if p.Exposed == nil {
+			continue
+		}
+		for _, combination := range p.Exposed.combinations {
+			switch comb := combination.(type) {
+			case Chow:
+				tiles.add(comb.FirstTile)
+				tiles.add(comb.FirstTile + 1)
+				tiles.add(comb.FirstTile + 2)
+			case Pung:
+				for i := 0; i < 3; i++ {
+					tiles.add(comb.Tile)
+				}
+			case Kong:
+				for i := 0; i < 4; i++ {
+					tiles.add(comb.Tile)
+				}
+			case BonusTile:
+				tiles.add(comb.Tile)
+			}
+		}
+	}
+
+	if tiles.Size() != set.Size() {
+		return fmt.Errorf("[%d] tiles on the table, the tile set has [%d]", tiles.Size(), set.Size())
+	}
+	for tile, count := range set.tiles {
+		if tiles.NumOf(tile) != int(count) {
+			return fmt.Errorf("[%d] tiles [%d] on the table, the tile set has [%d]", tiles.NumOf(tile), tile, count)
+		}
+	}
+	return nil
+}
+
+func (s playerSnapshot) validate(set *TileCollection, active bool, players int) error {
+	if s.Wind < East || s.Wind > North {
+		return fmt.Errorf("unknown wind [%d]", s.Wind)
+	}
+	if s.Received != nil && !active {
+		return fmt.Errorf("received a tile while not active")
+	}
+	if s.MissingSuit != 0 && (!s.MissingSuit.IsSuit() || s.MissingSuit.Suit() != s.MissingSuit) {
+		return fmt.Errorf("missing suit [%d] is not a suit", s.MissingSuit)
+	}
+
+	if err := checkTiles(set, optionalTiles(s.Received)...); err != nil {
+		return err
+	}
+	for _, c := range []*TileCollection{s.Concealed, s.Discarded} {
+		if err := checkCollection(set, c); err != nil {
+			return err
+		}
+	}
+	if err := checkCombinations(set, s.Exposed); err != nil {
+		return err
+	}
+	for _, d := range s.Discards {
+		if err := checkTiles(set, d.Tile); err != nil {
+			return err
+		}
+		if d.ClaimedBy != -1 && !isSeat(d.ClaimedBy, players) {
+			return fmt.Errorf("discard claimed by [%d], which is not a seat", d.ClaimedBy)
+		}
+	}
+	for tile := range s.RedFives {
+		if err := checkTiles(set, tile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isSeat(seat int, players int) bool {
+	return seat >= 0 && seat < players
+}
+
+func optionalTiles(tiles ...*Tile) []Tile {
+	present := make([]Tile, 0, len(tiles))
+	for _, tile := range tiles {
+		if tile != nil {
+			present = append(present, *tile)
+		}
+	}
+	return present
+}
+
+// Every tile has to be part of the tile set. This also keeps tiles below maxTile, which hands are evaluated on.
+func checkTiles(set *TileCollection, tiles ...Tile) error {
+	for _, tile := range tiles {
+		if set.NumOf(tile) == 0 {
+			return fmt.Errorf("tile [%d] is not part of the tile set", tile)
+		}
+	}
+	return nil
+}
+
+func checkCollection(set *TileCollection, c *TileCollection) error {
+	if c == nil {
+		return nil
+	}
+	for tile, count := range c.tiles {
+		if int(count) > set.NumOf(tile) {
+			return fmt.Errorf("[%d] tiles [%d], the tile set has [%d]", count, tile, set.NumOf(tile))
+		}
+	}
+	return nil
+}
+
+func checkCombinations(set *TileCollection, c *CombinationCollection) error {
+	if c == nil {
+		return nil
+	}
+	for _, combination := range c.combinations {
+		var err error
+		switch comb := combination.(type) {
+		case Chow:
+			if !comb.FirstTile.IsSuit() || comb.FirstTile%10 > 7 {
+				return fmt.Errorf("no chow starts at tile [%d]", comb.FirstTile)
+			}
+			err = checkTiles(set, comb.FirstTile, comb.FirstTile+1, comb.FirstTile+2)
+		case Pung:
+			err = checkTiles(set, comb.Tile)
+		case Kong:
+			err = checkTiles(set, comb.Tile)
+		case BonusTile:
+			err = checkTiles(set, comb.Tile)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Wall) snapshot() wallSnapshot {
+	return wallSnapshot{
+		Live:       wallTileSnapshots(w.live),
+		Dead:       wallTileSnapshots(w.dead),
+		Indicators: wallTileSnapshots(w.indicators),
+	}
+}
+
+func (s wallSnapshot) restore() *Wall {
+	return &Wall{
+		live:       restoreWallTiles(s.Live),
+		dead:       restoreWallTiles(s.Dead),
+		indicators: restoreWallTiles(s.Indicators),
+	}
+}
+
+func wallTileSnapshots(tiles []wallTile) []wallTileSnapshot {
+	snapshots := make([]wallTileSnapshot, len(tiles))
+	for i, t := range tiles {
+		snapshots[i] = wallTileSnapshot{Tile: t.tile, Red: t.red}
+	}
+	return snapshots
+}
+
+func restoreWallTiles(snapshots []wallTileSnapshot) []wallTile {
+	tiles := make([]wallTile, len(snapshots))
+	for i, s := range snapshots {
+		tiles[i] = wallTile{tile: s.Tile, red: s.Red}
+	}
+	return tiles
+}
+
+func (p *Player) snapshot() playerSnapshot {
+	redFives := make(map[Tile]int, len(p.redFives))
+	for tile, n := range p.redFives {
+		redFives[tile] = n
+	}
+
+	return playerSnapshot{
+		Score:           p.score,
+		Received:        copyTile(p.received),
+		Wind:            p.wind,
+		Concealed:       p.concealed.copy(),
+		Exposed:         p.exposed.copy(),
+		Discarded:       p.discarded.copy(),
+		Discards:        append([]DiscardedTile{}, p.discards...),
+		PassedOnWin:     p.passedOnWin,
+		Riichi:          p.riichi,
+		Ippatsu:         p.ippatsu,
+		RedFives:        redFives,
+		ExposedRedFives: p.exposedRedFives,
+		MissingSuit:     p.missingSuit,
+		Won:             p.won,
+	}
+}
+
+func (s playerSnapshot) restore() *Player {
+	p := &Player{
+		score:           s.Score,
+		received:        s.Received,
+		wind:            s.Wind,
+		concealed:       s.Concealed,
+		exposed:         s.Exposed,
+		discarded:       s.Discarded,
+		discards:        s.Discards,
+		passedOnWin:     s.PassedOnWin,
+		riichi:          s.Riichi,
+		ippatsu:         s.Ippatsu,
+		redFives:        s.RedFives,
+		exposedRedFives: s.ExposedRedFives,
+		missingSuit:     s.MissingSuit,
+		won:             s.Won,
+	}
+
+	if p.concealed == nil {
+		p.concealed = newEmptyTileCollection()
+	}
+	if p.exposed == nil {
+		p.exposed = newCombinationCollection()
+	}
+	if p.discarded == nil {
+		p.discarded = newEmptyTileCollection()
+	}
+	if p.discards == nil {
+		p.discards = make([]DiscardedTile, 0)
+	}
+	if p.redFives == nil {
+		p.redFives = make(map[Tile]int)
+	}
+	return p
+}
+
+func copyTile(tile *Tile) *Tile {
+	if tile == nil {
+		return nil
+	}
+	c := *tile
+	return &c
+}
+
+// JSON encoding of the collections, which keep their contents unexported.
+
+// A tile collection is encoded as the number of tiles per kind.
+func (t *TileCollection) MarshalJSON() ([]byte, error) {
+	counts := make(map[string]uint8, len(t.tiles))
+	for tile, count := range t.tiles {
+		counts[strconv.Itoa(int(tile))] = count
+	}
+	return json.Marshal(counts)
+}
+
+func (t *TileCollection) UnmarshalJSON(data []byte) error {
+	var counts map[string]uint8
+	err := json.Unmarshal(data, &counts)
+	if err != nil {
+		return err
+	}
+
+	t.tiles = make(map[Tile]uint8, len(counts))
+	for key, count := range counts {
+		tile, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("invalid tile [%s]", key)
+		}
+		t.tiles[Tile(tile)] = count
+	}
+	return nil
+}
+
+// combinationSnapshot is the encoding of a single combination.
+type combinationSnapshot struct {
+	Kind      string `json:"kind"`
+	Tile      Tile   `json:"tile"`
+	Concealed bool   `json:"concealed,omitempty"`
+}
+
+func combinationSnapshots(combinations []Combination) []combinationSnapshot {
+	snapshots := make([]combinationSnapshot, len(combinations))
+	for i, c := range combinations {
+		switch comb := c.(type) {
+		case Chow:
+			snapshots[i] = combinationSnapshot{Kind: "chow", Tile: comb.FirstTile}
+		case Pung:
+			snapshots[i] = combinationSnapshot{Kind: "pung", Tile: comb.Tile}
+		case Kong:
+			snapshots[i] = combinationSnapshot{Kind: "kong", Tile: comb.Tile, Concealed: comb.Concealed}
+		case BonusTile:
+			snapshots[i] = combinationSnapshot{Kind: "bonus", Tile: comb.Tile}
+		}
+	}
+	return snapshots
+}
+
+func restoreCombinations(snapshots []combinationSnapshot) ([]Combination, error) {
+	combinations := make([]Combination, len(snapshots))
+	for i, s := range snapshots {
+		switch s.Kind {
+		case "chow":
+			combinations[i] = Chow{FirstTile: s.Tile}
+		case "pung":
+			combinations[i] = Pung{Tile: s.Tile}
+		case "kong":
+			combinations[i] = Kong{Tile: s.Tile, Concealed: s.Concealed}
+		case "bonus":
+			combinations[i] = BonusTile{Tile: s.Tile}
+		default:
+			return nil, fmt.Errorf("unknown combination [%s]", s.Kind)
+		}
+	}
+	return combinations, nil
+}
+
+// A combination collection is encoded as the list of its combinations.
+func (c *CombinationCollection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(combinationSnapshots(c.combinations))
+}
+
+func (c *CombinationCollection) UnmarshalJSON(data []byte) error {
+	var snapshots []combinationSnapshot
+	err := json.Unmarshal(data, &snapshots)
+	if err != nil {
+		return err
+	}
+
+	c.combinations, err = restoreCombinations(snapshots)
+	return err
+}
+
+// decompositionSnapshot is the encoding of a decomposition, with its sets encoded as combinations.
+type decompositionSnapshot struct {
+	Form      HandForm              `json:"form"`
+	Pair      Tile                  `json:"pair"`
+	Pairs     []Tile                `json:"pairs"`
+	Knitted   []Tile                `json:"knitted"`
+	Concealed []combinationSnapshot `json:"concealed"`
+	Exposed   []combinationSnapshot `json:"exposed"`
+}
+
+func (d Decomposition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(decompositionSnapshot{
+		Form:      d.Form,
+		Pair:      d.Pair,
+		Pairs:     d.Pairs,
+		Knitted:   d.Knitted,
+		Concealed: combinationSnapshots(d.Concealed),
+		Exposed:   combinationSnapshots(d.Exposed),
+	})
+}
+
+func (d *Decomposition) UnmarshalJSON(data []byte) error {
+	var s decompositionSnapshot
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+
+	d.Form, d.Pair, d.Pairs, d.Knitted = s.Form, s.Pair, s.Pairs, s.Knitted
+	d.Concealed, err = restoreCombinations(s.Concealed)
+	if err != nil {
+		return err
+	}
+	d.Exposed, err = restoreCombinations(s.Exposed)
+	return err
+}
@@ -4,16 +4,28 @@ import (
 	"errors"
 	"fmt"
 	"github.com/roelofruis/mahjong-learn/state"
+	"sync"
+	"time"
 )
 
 type Game struct {
 	Table        *Table
 	StateMachine *state.StateMachine
+
+	lock sync.Mutex
+	log  *GameLog
+}
+
+// NewGame starts a game that is played by the given ruleset, with a random seed.
+func NewGame(rules Ruleset, transitioner state.Transitioner) (*Game, error) {
+	return NewSeededGame(rules, time.Now().UnixNano(), transitioner)
 }
 
-func NewGame(transitioner state.Transitioner) (*Game, error) {
-	table := newTable()
-	generator := stateNewGame(table)
+// NewSeededGame starts a game that is played by the given ruleset. Games with the same seed and the same actions are identical.
+func NewSeededGame(rules Ruleset, seed int64, transitioner state.Transitioner) (*Game, error) {
+	table := newTable(rules, seed)
+	log := newGameLog(table)
+	generator := table.states.newGame(table)
 
 	sm := state.NewStateMachine(generator, transitioner)
 
@@ -25,73 +37,100 @@ func NewGame(transitioner state.Transitioner) (*Game, error) {
 	return &Game{
 		Table:        table,
 		StateMachine: sm,
+		log:          log,
 	}, nil
 }
 
 type stateGenerator func(table *Table) *state.State
 
-var (
-	stateNewGame       stateGenerator
-	stateNextRound     stateGenerator
-	stateNextTurn      stateGenerator
-	stateMustDiscard   stateGenerator
-	stateTileDiscarded stateGenerator
-	stateGameEnded     stateGenerator
-)
-
-func init() {
-	// initialize states in `init` to prevent loops in references
-	stateNewGame = func(table *Table) *state.State {
-		return state.NewIntermediateState("New Game", table.initialize)
-	}
-
-	stateNextRound = func(table *Table) *state.State {
-		return state.NewIntermediateState("Next Round", table.tryNextRound)
-	}
-
-	stateNextTurn = func(table *Table) *state.State {
-		return state.NewIntermediateState("Next turn", table.tryDealTile)
-	}
-
-	stateMustDiscard = func(table *Table) *state.State {
-		return state.NewState("Must Discard", table.mustDiscardActions(), table.handleMustDiscardActions)
-	}
+// stateSet holds the states a game moves through.
+// States refer to each other through the set of the table, so rulesets can replace individual states to change the flow of the game.
+type stateSet struct {
+	newGame   stateGenerator
+	nextRound stateGenerator
+	// The state after the tiles of a hand are dealt, before the first turn.
+	handDealt     stateGenerator
+	nextTurn      stateGenerator
+	mustDiscard   stateGenerator
+	tileDiscarded stateGenerator
+	kongAdded     stateGenerator
+	// The state after one or more players declared a win.
+	playerWon stateGenerator
+	gameEnded stateGenerator
+}
 
-	stateTileDiscarded = func(table *Table) *state.State {
-		return state.NewState("Tile Discarded", table.tileDiscardedActions(), table.handleTileDiscardedActions)
+// Wrap the states in which the game waits for actions, or has ended, so generating one records its generator on the table.
+func (s stateSet) recordingWaits() stateSet {
+	for _, generator := range []*stateGenerator{&s.handDealt, &s.mustDiscard, &s.tileDiscarded, &s.kongAdded, &s.gameEnded} {
+		generate := *generator
+		*generator = func(table *Table) *state.State {
+			table.waitingIn = generate
+			return generate(table)
+		}
 	}
+	return s
+}
 
-	stateGameEnded = func(table *Table) *state.State {
-		return state.NewTerminalState("Game Ended")
+// The states of a game in which the players take turns drawing and discarding until someone wins or the wall is exhausted.
+func standardStates() stateSet {
+	return stateSet{
+		newGame: func(table *Table) *state.State {
+			return state.NewIntermediateState("New Game", table.initialize)
+		},
+		nextRound: func(table *Table) *state.State {
+			return state.NewIntermediateState("Next Round", table.tryNextRound)
+		},
+		handDealt: func(table *Table) *state.State {
+			return table.states.nextTurn(table)
+		},
+		nextTurn: func(table *Table) *state.State {
+			return state.NewIntermediateState("Next turn", table.tryDealTile)
+		},
+		mustDiscard: func(table *Table) *state.State {
+			return state.NewState("Must Discard", table.mustDiscardActions(), table.handleMustDiscardActions)
+		},
+		tileDiscarded: func(table *Table) *state.State {
+			return state.NewState("Tile Discarded", table.tileDiscardedActions(), table.handleTileDiscardedActions)
+		},
+		kongAdded: func(table *Table) *state.State {
+			return state.NewState("Kong Added", table.kongAddedActions(), table.handleKongAddedActions)
+		},
+		playerWon: func(table *Table) *state.State {
+			return table.states.nextRound(table)
+		},
+		gameEnded: func(table *Table) *state.State {
+			return state.NewTerminalState("Game Ended")
+		},
 	}
 }
 
 func (t *Table) initialize() *state.State {
-	t.dealConcealed(13, 0)
-	t.dealConcealed(13, 1)
-	t.dealConcealed(13, 2)
-	t.dealConcealed(13, 3)
+	for _, s := range t.seats() {
+		t.dealConcealed(t.rules.HandSize, s)
+	}
 
-	return stateNextTurn(t)
+	return t.states.handDealt(t)
 }
 
 func (t *Table) tryDealTile() *state.State {
-	if t.GetWallSize() <= 14 {
-		return stateNextRound(t)
+	if t.GetWall().IsExhausted() {
+		return t.states.nextRound(t)
 	}
 
 	t.dealToActivePlayer()
 
-	return stateMustDiscard(t)
+	return t.states.mustDiscard(t)
 }
 
 func (t *Table) mustDiscardActions() map[int][]state.Action {
 	actionMap := make(map[int][]state.Action, 1)
 
 	if t.GetActivePlayer().GetReceivedTile() == nil {
-		actionMap[t.GetActivePlayerIndex()] = t.GetActivePlayer().getDiscardAfterCombinationActions()
+		actionMap[t.GetActivePlayerIndex()] = t.GetActivePlayer().getDiscardAfterCombinationActions(t.rules)
 	} else {
-		actionMap[t.GetActivePlayerIndex()] = t.GetActivePlayer().getTileReceivedActions()
+		activePlayer := t.GetActivePlayer()
+		canWin := t.canDeclareMahjong(t.GetActivePlayerIndex(), *activePlayer.received, -1)
+		actionMap[t.GetActivePlayerIndex()] = activePlayer.getTileReceivedActions(t.rules, canWin, t.activePlayerCanDeclareRiichi())
 	}
 
 	return actionMap
@@ -101,21 +140,24 @@ func (t *Table) handleMustDiscardActions(actions map[int]state.Action) (*state.S
 	switch a := actions[t.GetActivePlayerIndex()].(type) {
 	case Discard:
 		t.activePlayerDiscards(a.Tile)
-		return stateTileDiscarded(t), nil
+		return t.states.tileDiscarded(t), nil
+
+	case DeclareRiichi:
+		t.activePlayerDeclaresRiichi(a.Tile)
+		return t.states.tileDiscarded(t), nil
 
 	case DeclareConcealedKong:
 		t.activePlayerDeclaresConcealedKong(a.Tile)
-		t.dealToActivePlayer()
-		return stateMustDiscard(t), nil
+		t.dealReplacementToActivePlayer()
+		return t.states.mustDiscard(t), nil
 
 	case ExposedPungToKong:
-		t.activePlayerAddsToExposedPung()
-		t.dealToActivePlayer()
-		return stateMustDiscard(t), nil
+		t.activePlayerAnnouncesAddedKong()
+		return t.states.kongAdded(t), nil
 
 	case DeclareMahjong:
-		// TODO: double check no more logic is needed here
-		return stateNextRound(t), nil
+		t.activePlayerDeclaresMahjong()
+		return t.states.playerWon(t), nil
 
 	default:
 		return nil, fmt.Errorf("illegal action %+v", a)
@@ -128,8 +170,9 @@ func (t *Table) tileDiscardedActions() map[int][]state.Action {
 	activeDiscard := *t.GetActiveDiscard()
 
 	for s, p := range t.GetReactingPlayers() {
-		isNextPlayer := (t.GetActivePlayerIndex()+1)%4 == s
-		m[s] = p.getTileDiscardedActions(activeDiscard, isNextPlayer)
+		isNextPlayer := (t.GetActivePlayerIndex()+1)%len(t.players) == s
+		canWin := t.canDeclareMahjong(s, activeDiscard, t.GetActivePlayerIndex())
+		m[s] = p.getTileDiscardedActions(activeDiscard, isNextPlayer, canWin, t.rules)
 	}
 
 	return m
@@ -138,22 +181,15 @@ func (t *Table) tileDiscardedActions() map[int][]state.Action {
 func (t *Table) handleTileDiscardedActions(actions map[int]state.Action) (*state.State, error) {
 	var bestValue = 0
 	var bestPlayer int
-	for _, playerIndex := range []int{(t.GetActivePlayerIndex() + 1) % 4, (t.GetActivePlayerIndex() + 2) % 4, (t.GetActivePlayerIndex() + 3) % 4} {
-		var value int
-		switch actions[playerIndex].(type) {
-		case DoNothing:
-			value = 1
-		case DeclareChow:
-			value = 2
-		case DeclarePung:
-			value = 3
-		case DeclareKong:
-			value = 4
-		case DeclareMahjong:
-			value = 5
-		default:
+	var winners []int
+	for _, playerIndex := range t.GetReactingPlayerIndices() {
+		value := t.rules.claimPriority(actions[playerIndex])
+		if value == 0 {
 			return nil, errors.New("invalid action given in response to `handleTileDiscarded`")
 		}
+		if _, isMahjong := actions[playerIndex].(DeclareMahjong); isMahjong {
+			winners = append(winners, playerIndex)
+		}
 		if value > bestValue {
 			bestValue = value
 			bestPlayer = playerIndex
@@ -161,50 +197,167 @@ func (t *Table) handleTileDiscardedActions(actions map[int]state.Action) (*state
 	}
 	bestAction := actions[bestPlayer]
 
+	t.playersPassOnWinningTile(*t.GetActiveDiscard(), actions)
+
+	if _, isMahjong := bestAction.(DeclareMahjong); !isMahjong {
+		t.activePlayerPaysRiichiDeposit()
+	}
+
 	switch a := bestAction.(type) {
 	case DoNothing:
 		t.activePlayerTakesDiscarded()
 		t.makePlayerActive(bestPlayer)
-		return stateNextTurn(t), nil
+		return t.states.nextTurn(t), nil
 
 	case DeclareChow:
-		t.makePlayerActive(bestPlayer)
+		t.playerClaimsDiscard(bestPlayer)
 		t.activePlayerTakesChow(a.Tile)
-		return stateMustDiscard(t), nil
+		return t.states.mustDiscard(t), nil
 
 	case DeclarePung:
-		t.makePlayerActive(bestPlayer)
+		t.playerClaimsDiscard(bestPlayer)
 		t.activePlayerTakesPung()
-		return stateMustDiscard(t), nil
+		return t.states.mustDiscard(t), nil
 
 	case DeclareKong:
-		t.makePlayerActive(bestPlayer)
+		t.playerClaimsDiscard(bestPlayer)
 		t.activePlayerTakesKong()
-		t.dealToActivePlayer()
-		return stateMustDiscard(t), nil
+		t.dealReplacementToActivePlayer()
+		return t.states.mustDiscard(t), nil
 
 	case DeclareMahjong:
-		// TODO: double check no more logic is needed here
-		return stateNextRound(t), nil
+		t.playersDeclareMahjongOnDiscard(winners)
+		return t.states.playerWon(t), nil
 	}
 
 	return nil, fmt.Errorf("invalid state encountered after resolving tile discarded.\nall actions %+v\nbest action %+v", actions, bestAction)
 }
 
-func (t *Table) tryNextRound() *state.State {
-	// TODO: tally scores
+func (t *Table) kongAddedActions() map[int][]state.Action {
+	m := make(map[int][]state.Action, 3)
+
+	addedTile := *t.GetAddedKongTile()
+
+	for s, p := range t.GetReactingPlayers() {
+		m[s] = p.getKongAddedActions(t.canDeclareMahjong(s, addedTile, t.GetActivePlayerIndex()))
+	}
+
+	return m
+}
 
-	// Game ends if player 3 has been North
-	if t.GetPrevalentWind() == North && t.GetPlayerByIndex(3).GetWind() == North {
-		return stateGameEnded(t)
+func (t *Table) handleKongAddedActions(actions map[int]state.Action) (*state.State, error) {
+	var winners []int
+	for _, playerIndex := range t.GetReactingPlayerIndices() {
+		switch actions[playerIndex].(type) {
+		case DoNothing:
+			continue
+
+		case DeclareMahjong:
+			winners = append(winners, playerIndex)
+
+		default:
+			return nil, errors.New("invalid action given in response to `handleKongAdded`")
+		}
 	}
 
-	if t.GetPlayerByIndex(3).GetWind() == t.GetPrevalentWind() {
-		t.setNextPrevalentWind()
+	if len(winners) > 0 {
+		t.playersRobKong(winners)
+		return t.states.playerWon(t), nil
+	}
+
+	t.playersPassOnWinningTile(*t.GetAddedKongTile(), actions)
+
+	t.activePlayerAddsToExposedPung()
+	t.dealReplacementToActivePlayer()
+
+	return t.states.mustDiscard(t), nil
+}
+
+func (t *Table) tryNextRound() *state.State {
+	t.settleRound()
+
+	if !t.advanceHand() {
+		return t.states.gameEnded(t)
 	}
 
 	t.resetWall()
 	t.prepareNextRound()
 
-	return stateNextTurn(t)
+	return t.states.handDealt(t)
+}
+
+// States of the Sichuan game: every hand starts with the players declaring a missing suit,
+// and after a win the other players play on until one of them is left or the wall is exhausted.
+func sichuanStates(states *stateSet) {
+	states.handDealt = func(table *Table) *state.State {
+		return state.NewState("Declare Missing Suit", table.missingSuitActions(), table.handleMissingSuitActions)
+	}
+	states.playerWon = func(table *Table) *state.State {
+		return state.NewIntermediateState("Player Won", table.tryContinueAfterWin)
+	}
+}
+
+// States of the American game: every hand starts with the Charleston, in which the players pass tiles to the right, across and to the left.
+func americanStates(states *stateSet) {
+	states.handDealt = func(table *Table) *state.State {
+		return state.NewState("Charleston", table.charlestonActions(), table.handleCharlestonActions)
+	}
+}
+
+func (t *Table) charlestonActions() map[int][]state.Action {
+	actionMap := make(map[int][]state.Action, len(t.players))
+
+	for s, p := range t.players {
+		actionMap[s] = p.getCharlestonActions()
+	}
+
+	return actionMap
+}
+
+func (t *Table) handleCharlestonActions(actions map[int]state.Action) (*state.State, error) {
+	passes := make(map[int]PassTiles, len(t.players))
+	for _, s := range t.seats() {
+		pass, isPass := actions[s].(PassTiles)
+		if !isPass {
+			return nil, fmt.Errorf("illegal action %+v", actions[s])
+		}
+		passes[s] = pass
+	}
+
+	t.playersPassTiles(passes)
+
+	if t.charlestonPasses < len(charlestonDirections) {
+		return t.states.handDealt(t), nil
+	}
+	return t.states.nextTurn(t), nil
+}
+
+func (t *Table) missingSuitActions() map[int][]state.Action {
+	actionMap := make(map[int][]state.Action, len(t.players))
+
+	for s, p := range t.players {
+		actionMap[s] = p.getMissingSuitActions()
+	}
+
+	return actionMap
+}
+
+func (t *Table) handleMissingSuitActions(actions map[int]state.Action) (*state.State, error) {
+	for _, s := range t.seats() {
+		a, isDeclaration := actions[s].(DeclareMissingSuit)
+		if !isDeclaration {
+			return nil, fmt.Errorf("illegal action %+v", actions[s])
+		}
+		t.players[s].missingSuit = a.Suit
+	}
+
+	return t.states.nextTurn(t), nil
+}
+
+func (t *Table) tryContinueAfterWin() *state.State {
+	if !t.winnersLeaveHand() {
+		return t.states.nextRound(t)
+	}
+
+	return t.states.nextTurn(t)
 }
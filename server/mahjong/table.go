@@ -1,5 +1,11 @@
 package mahjong
 
+import (
+	"github.com/roelofruis/mahjong-learn/state"
+	"math/rand"
+	"sort"
+)
+
 type Wind int
 
 const (
@@ -9,52 +15,163 @@ const (
 	North Wind = 3
 )
 
+// The wind tile that belongs to this wind.
+func (w Wind) Tile() Tile {
+	return EastWind + Tile(w)
+}
+
 type Table struct {
 	prevalentWind Wind
-	wall          *TileCollection
+	dealer        int
+	// Number of hands that were completed in this game.
+	handsPlayed int
+	// Number of hands in a row the dealer kept the seat or that ended in a draw.
+	repeats       int
+	wall          *Wall
 	activeDiscard *Tile
 	players       map[int]*Player
 	activePlayer  int
+
+	// Whether the tile the active player received came from the dead wall.
+	receivedReplacement bool
+	// Tile the active player is adding to an exposed pung, which the other players may still claim to win.
+	addedKongTile *Tile
+	// Whether the active discard and the tile being added to a kong are red fives.
+	activeDiscardRed bool
+	addedKongRed     bool
+	// Whether the active discard was made while declaring riichi, so the deposit is paid once nobody wins on it.
+	riichiDiscard bool
+	// Whether any claim or kong broke the turn order this round.
+	interrupted bool
+	// Number of dora indicators that are revealed.
+	revealedIndicators int
+	// Riichi deposits on the table, which go to the next winner.
+	deposits int
+	// Number of passes of the Charleston made this hand.
+	charlestonPasses int
+
+	rules  Ruleset
+	states stateSet
+	// Generator of the state the game waits in, so that state can be generated again for a copy of the table.
+	waitingIn stateGenerator
+	// Seed of the random source that shuffles the walls. The same seed and the same actions always give the same game.
+	seed   int64
+	random *randomSource
+	rng    *rand.Rand
+	// Wins declared in the current round, scored when the round ends.
+	wins []Win
+	// Whether the current round ended without a winner before the wall was exhausted.
+	abortive  bool
+	lastRound *RoundResult
 }
 
-func newTable() *Table {
-	players := make(map[int]*Player, 4)
+func newTable(rules Ruleset, seed int64) *Table {
+	players := make(map[int]*Player, rules.Players)
 
-	wall := newMahjongSet()
-	players[0] = newPlayer(East)
-	players[1] = newPlayer(South)
-	players[2] = newPlayer(West)
-	players[3] = newPlayer(North)
+	for i := 0; i < rules.Players; i++ {
+		players[i] = newPlayer(Wind(i))
+		players[i].score = rules.StartingScore
+	}
 
-	return &Table{
+	table := &Table{
 		prevalentWind: East,
-		wall:          wall,
+		dealer:        0,
+		handsPlayed:   0,
+		repeats:       0,
+		wall:          nil,
 		activeDiscard: nil,
 		players:       players,
 		activePlayer:  0,
+
+		receivedReplacement: false,
+		addedKongTile:       nil,
+		activeDiscardRed:    false,
+		addedKongRed:        false,
+		riichiDiscard:       false,
+		interrupted:         false,
+		revealedIndicators:  0,
+		deposits:            0,
+		charlestonPasses:    0,
+
+		rules:     rules,
+		states:    rules.states(),
+		seed:      seed,
+		wins:      nil,
+		abortive:  false,
+		lastRound: nil,
 	}
+
+	table.setRandomSource(newRandomSource(seed))
+	table.resetWall()
+
+	return table
+}
+
+// Shuffle the walls with the given source from now on.
+func (t *Table) setRandomSource(source *randomSource) {
+	t.random = source
+	t.rng = rand.New(source)
 }
 
 // Getters
 
+// Number of tiles that can still be drawn from the live wall.
 func (t *Table) GetWallSize() int {
-	return t.wall.Size()
+	return t.wall.LiveSize()
 }
 
 func (t *Table) GetActivePlayerIndex() int {
 	return t.activePlayer
 }
 
+// Number of players at the table.
+func (t *Table) GetPlayerCount() int {
+	return len(t.players)
+}
+
 func (t *Table) GetReactingPlayers() map[int]*Player {
-	reactingPlayers := make(map[int]*Player, 3)
+	reactingPlayers := make(map[int]*Player, len(t.players)-1)
 	for s, p := range t.players {
-		if s != t.activePlayer {
+		if s != t.activePlayer && !p.won {
 			reactingPlayers[s] = p
 		}
 	}
 	return reactingPlayers
 }
 
+// Seats of the players other than the active player that are still in the hand, in turn order starting from the player after the active player.
+func (t *Table) GetReactingPlayerIndices() []int {
+	indices := make([]int, 0, len(t.players)-1)
+	for i := 1; i < len(t.players); i++ {
+		s := (t.activePlayer + i) % len(t.players)
+		if !t.players[s].won {
+			indices = append(indices, s)
+		}
+	}
+	return indices
+}
+
+// All seats at the table, in order.
+func (t *Table) seats() []int {
+	seats := make([]int, 0, len(t.players))
+	for s := range t.players {
+		seats = append(seats, s)
+	}
+	sort.Ints(seats)
+	return seats
+}
+
+// Seats of the players that did not win and leave the hand, in order.
+func (t *Table) seatsInHand() []int {
+	seats := make([]int, 0, len(t.players))
+	for _, s := range t.seats() {
+		if !t.players[s].won {
+			seats = append(seats, s)
+		}
+	}
+	return seats
+}
+
 func (t *Table) GetActivePlayer() *Player {
 	return t.GetPlayerByIndex(t.activePlayer)
 }
@@ -71,41 +188,124 @@ func (t *Table) GetActiveDiscard() *Tile {
 	return t.activeDiscard
 }
 
-func (t *Table) GetWall() *TileCollection {
+// The tile that is being added to an exposed pung, nil if no kong is being added.
+func (t *Table) GetAddedKongTile() *Tile {
+	return t.addedKongTile
+}
+
+func (t *Table) GetWall() *Wall {
 	return t.wall
 }
 
+func (t *Table) GetRuleset() Ruleset {
+	return t.rules
+}
+
+// The seed the game was started with.
+func (t *Table) GetSeed() int64 {
+	return t.seed
+}
+
+// The result of the previous round, nil if no round has ended yet.
+func (t *Table) GetLastRoundResult() *RoundResult {
+	return t.lastRound
+}
+
+// The tiles that would complete the hand of the player.
+func (t *Table) GetWaitingTiles(player int) []Tile {
+	return t.rules.WaitingTiles(t.players[player].concealed, t.players[player].exposed)
+}
+
+// Whether the player may not win on a discard, because they discarded one of the tiles they are waiting on,
+// or let a winning tile pass since their last discard. Always false if the ruleset does not use furiten.
+func (t *Table) IsFuriten(player int) bool {
+	return t.rules.Furiten && t.players[player].isFuriten(t.rules)
+}
+
+// The revealed dora indicators.
+func (t *Table) GetDoraIndicators() []Tile {
+	return t.wall.Indicators()[:t.revealedIndicators]
+}
+
+// The ura dora indicators below the revealed dora indicators, which only count for players that declared riichi.
+func (t *Table) GetUraDoraIndicators() []Tile {
+	indicators := t.wall.Indicators()
+	if len(indicators) < 2*t.rules.DoraIndicators {
+		return nil
+	}
+	return indicators[t.rules.DoraIndicators : t.rules.DoraIndicators+t.revealedIndicators]
+}
+
+// Riichi deposits on the table, which go to the next winner.
+func (t *Table) GetDeposits() int {
+	return t.deposits
+}
+
+// Seat of the player that is East in this round.
+func (t *Table) GetDealerIndex() int {
+	return t.dealer
+}
+
+// The number of the current hand within the prevalent wind, starting at 1.
+func (t *Table) GetHandNumber() int {
+	return t.dealer + 1
+}
+
+// Number of hands that were completed in this game.
+func (t *Table) GetHandsPlayed() int {
+	return t.handsPlayed
+}
+
+// Number of hands in a row the dealer kept the seat or that ended in a draw.
+func (t *Table) GetRepeatCounter() int {
+	return t.repeats
+}
+
 // State Updates
 
+// Deal the next tile from the live wall to the active player.
 func (t *Table) dealToActivePlayer() {
-	activePlayer := t.GetActivePlayer()
+	tile, red := t.wall.draw()
+	t.receiveTile(tile, red, false)
+}
 
-	for {
-		wallTile := t.wall.removeRandom()
+// Deal a replacement tile from the dead wall to the active player, after declaring a kong.
+func (t *Table) dealReplacementToActivePlayer() {
+	tile, red := t.wall.drawReplacement()
+	t.receiveTile(tile, red, true)
+}
 
-		if !wallTile.IsBonusTile() {
-			activePlayer.received = &wallTile
-			break
-		}
+func (t *Table) receiveTile(tile Tile, red bool, isReplacement bool) {
+	activePlayer := t.GetActivePlayer()
+
+	for t.rules.tileSet().isBonusTile(tile) {
+		activePlayer.exposed.add(BonusTile{tile})
+		tile, red = t.wall.drawReplacement()
+		isReplacement = true
+	}
 
-		activePlayer.exposed.add(BonusTile{wallTile})
+	if red {
+		activePlayer.redFives[tile]++
 	}
+	activePlayer.received = &tile
+	t.receivedReplacement = isReplacement
 }
 
 func (t *Table) dealConcealed(n int, player int) {
 	activePlayer := t.players[player]
 
 	for i := n; i > 0; i-- {
-		for {
-			wallTile := t.wall.removeRandom()
-
-			if !wallTile.IsBonusTile() {
-				activePlayer.concealed.add(wallTile)
-				break
-			}
+		wallTile, red := t.wall.draw()
 
+		for t.rules.tileSet().isBonusTile(wallTile) {
 			activePlayer.exposed.add(BonusTile{wallTile})
+			wallTile, red = t.wall.drawReplacement()
+		}
+
+		if red {
+			activePlayer.redFives[wallTile]++
 		}
+		activePlayer.concealed.add(wallTile)
 	}
 }
 
@@ -114,20 +314,459 @@ func (t *Table) setNextPrevalentWind() {
 }
 
 func (t *Table) resetWall() {
-	t.wall = newMahjongSet()
+	t.wall = t.rules.newWall(t.rng)
+	t.revealedIndicators = 0
+	if t.rules.DoraIndicators > 0 {
+		t.revealedIndicators = 1
+	}
+}
+
+// Reveal the next dora indicator after a kong was declared.
+func (t *Table) revealKongIndicator() {
+	if t.revealedIndicators > 0 && t.revealedIndicators < t.rules.DoraIndicators {
+		t.revealedIndicators++
+	}
+}
+
+// A claim or kong breaks the turn order, which ends the chance of winning with ippatsu or on the first draw.
+func (t *Table) interruptTurnOrder() {
+	t.interrupted = true
+	for _, p := range t.players {
+		p.ippatsu = false
+	}
+}
+
+// Move on to the next hand, based on how the previous round ended.
+// Returns false if the game is over.
+func (t *Table) advanceHand() bool {
+	t.handsPlayed++
+
+	if t.rules.Length == MatchSingleHand || t.isBust() {
+		return false
+	}
+	if t.inOvertime() && t.reachedTarget() {
+		return false
+	}
+
+	if t.dealerKeepsSeat() {
+		t.repeats++
+		return true
+	}
+
+	if len(t.lastRound.Wins) == 0 {
+		t.repeats++
+	} else {
+		t.repeats = 0
+	}
+
+	t.dealer = (t.dealer + 1) % len(t.players)
+	if t.dealer == 0 {
+		lastWind := int(t.prevalentWind)+1 >= t.rules.Length.prevalentWinds()
+		if lastWind && (t.reachedTarget() || t.prevalentWind == North) {
+			return false
+		}
+		t.setNextPrevalentWind()
+	}
+
+	return true
+}
+
+// Whether a player's score dropped below zero, in rulesets where that ends the game.
+func (t *Table) isBust() bool {
+	if !t.rules.BustEnds {
+		return false
+	}
+	for _, p := range t.players {
+		if p.score < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the game went on beyond the match length, because nobody reached the overtime target.
+func (t *Table) inOvertime() bool {
+	return int(t.prevalentWind) >= t.rules.Length.prevalentWinds()
+}
+
+// Whether a player reached the overtime target, always true if the ruleset has no overtime.
+func (t *Table) reachedTarget() bool {
+	if t.rules.OvertimeTarget == 0 {
+		return true
+	}
+	for _, p := range t.players {
+		if p.score >= t.rules.OvertimeTarget {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the dealer keeps the seat after the previous round, as decided by the ruleset.
+func (t *Table) dealerKeepsSeat() bool {
+	if t.lastRound.Abortive {
+		return true
+	}
+
+	dealerWon := false
+	for _, w := range t.lastRound.Wins {
+		if w.Context.Winner == t.dealer {
+			dealerWon = true
+		}
+	}
+	isDraw := len(t.lastRound.Wins) == 0
+
+	switch t.rules.DealerRetention {
+	case DealerKeepsOnWin:
+		return dealerWon
+	case DealerKeepsOnWinOrTenpai:
+		if isDraw {
+			for _, s := range t.lastRound.Tenpai {
+				if s == t.dealer {
+					return true
+				}
+			}
+		}
+		return dealerWon
+	case DealerKeepsOnWinOrDraw:
+		return dealerWon || isDraw
+	default:
+		return false
+	}
 }
 
 func (t *Table) prepareNextRound() {
-	for s, p := range t.players {
+	t.activeDiscard = nil
+	t.addedKongTile = nil
+	t.activeDiscardRed = false
+	t.addedKongRed = false
+	t.riichiDiscard = false
+	t.interrupted = false
+	t.charlestonPasses = 0
+	t.activePlayer = t.dealer
+
+	// deal in seat order, so the same wall always gives the same hands
+	for _, s := range t.seats() {
+		p := t.players[s]
 		p.received = nil
 		p.discarded.empty()
+		p.discards = make([]DiscardedTile, 0)
+		p.passedOnWin = false
+		p.riichi = RiichiNone
+		p.ippatsu = false
+		p.redFives = make(map[Tile]int)
+		p.exposedRedFives = 0
+		p.missingSuit = 0
+		p.won = false
 		p.concealed.empty()
 		p.exposed.empty()
-		p.wind = (p.wind + 5) % 4
-		t.dealConcealed(13, s)
+		p.wind = Wind((s - t.dealer + len(t.players)) % len(t.players))
+		t.dealConcealed(t.rules.HandSize, s)
+	}
+}
+
+func (t *Table) activePlayerDeclaresMahjong() {
+	t.addWin(t.activePlayer, -1, *t.GetActivePlayer().received)
+}
+
+// The given players claim the active discard to win, as resolved by the multiple win policy of the ruleset.
+func (t *Table) playersDeclareMahjongOnDiscard(players []int) {
+	if t.activeDiscard != nil {
+		for _, player := range t.resolveWinningClaims(players) {
+			t.addWin(player, t.activePlayer, *t.activeDiscard)
+		}
+	}
+}
+
+// The given players claim the tile being added to a kong to win, as resolved by the multiple win policy of the ruleset.
+func (t *Table) playersRobKong(players []int) {
+	if t.addedKongTile != nil {
+		for _, player := range t.resolveWinningClaims(players) {
+			t.addWin(player, t.activePlayer, *t.addedKongTile)
+		}
 	}
 }
 
+// Which of the players claiming a win, given in turn order, actually win. If none do, the round ends in an abortive draw.
+func (t *Table) resolveWinningClaims(players []int) []int {
+	switch t.rules.MultipleWins {
+	case MultipleWinsAllowed:
+		return players
+	case MultipleWinsAbortOnTriple:
+		if len(players) >= 3 {
+			t.abortive = true
+			return nil
+		}
+		return players
+	default:
+		return players[:1]
+	}
+}
+
+// The players that declared a win leave the hand, the first of them takes the winning tile. The others play on from the seat after the last winner.
+// Returns false if the hand is over, because at most one player is left or the wall is exhausted.
+func (t *Table) winnersLeaveHand() bool {
+	if t.abortive {
+		return false
+	}
+
+	lastWinner := -1
+	tileTaken := false
+	for _, win := range t.wins {
+		winner := t.players[win.Context.Winner]
+		if winner.won {
+			continue
+		}
+		winner.won = true
+
+		if win.Context.IsSelfDrawn() {
+			if winner.received != nil {
+				winner.concealed.add(*winner.received)
+				winner.received = nil
+			}
+		} else if !tileTaken {
+			// only the first player that won on the discard or the added kong tile takes it,
+			// the others are scored from the copy of their hand in the win
+			tileTaken = true
+			winner.concealed.add(win.Context.WinningTile)
+			discards := t.players[win.Context.Discarder].discards
+			if t.activeDiscard != nil && len(discards) > 0 && discards[len(discards)-1].ClaimedBy == -1 {
+				discards[len(discards)-1].ClaimedBy = win.Context.Winner
+			}
+		}
+		lastWinner = win.Context.Winner
+	}
+
+	t.activeDiscard = nil
+	t.addedKongTile = nil
+	t.activeDiscardRed = false
+	t.addedKongRed = false
+	t.interruptTurnOrder()
+
+	if len(t.seatsInHand()) <= 1 || t.wall.IsExhausted() {
+		return false
+	}
+
+	t.makePlayerActive(t.nextSeatInHand(lastWinner))
+	return true
+}
+
+// The first seat after the given seat, in turn order, of a player that is still in the hand.
+func (t *Table) nextSeatInHand(seat int) int {
+	for i := 1; i < len(t.players); i++ {
+		s := (seat + i) % len(t.players)
+		if !t.players[s].won {
+			return s
+		}
+	}
+	return seat
+}
+
+// Record a win. The winning tile stays where it is, it is only added to a copy of the winner's hand.
+// This way several players can win on the same tile.
+func (t *Table) addWin(winner int, discarder int, winningTile Tile) {
+	t.wins = append(t.wins, t.newWin(winner, discarder, winningTile))
+}
+
+// Whether the player can declare mahjong with the tile, which was discarded by the discarder or drawn if the discarder is -1.
+// Besides completing the hand, the win has to be allowed by the furiten rule and the requirements of the scorer.
+func (t *Table) canDeclareMahjong(player int, tile Tile, discarder int) bool {
+	p := t.players[player]
+
+	if discarder != -1 && !t.rules.allowsClaim(ClaimMahjong) {
+		return false
+	}
+
+	if discarder != -1 && tile.IsJoker() {
+		return false
+	}
+
+	if p.isMissingSuit(tile) || p.holdsMissingSuit() || !t.rules.isWinningHand(p.concealed, p.exposed, tile) {
+		return false
+	}
+
+	if discarder != -1 && t.rules.Furiten && p.isFuriten(t.rules) {
+		return false
+	}
+
+	if requirement, has := t.rules.Scorer.(WinRequirement); has {
+		win := t.newWin(player, discarder, tile)
+		return requirement.AllowsWin(win.Hand, win.Context)
+	}
+
+	return true
+}
+
+func (t *Table) newWin(winner int, discarder int, winningTile Tile) Win {
+	player := t.players[winner]
+
+	concealed := player.concealed.copy()
+	concealed.add(winningTile)
+
+	return Win{
+		Context: t.winContext(winner, discarder, winningTile),
+		Hand: WinningHand{
+			Concealed:      concealed,
+			Exposed:        player.exposed,
+			Decompositions: t.rules.decompose(concealed, player.exposed),
+		},
+	}
+}
+
+func (t *Table) winContext(winner int, discarder int, winningTile Tile) WinContext {
+	player := t.players[winner]
+
+	bonusTiles := make([]Tile, 0)
+	for _, c := range player.exposed.combinations {
+		if b, isBonus := c.(BonusTile); isBonus {
+			bonusTiles = append(bonusTiles, b.Tile)
+		}
+	}
+
+	robbingKong := discarder != -1 && t.addedKongTile != nil
+
+	redFives := player.GetRedFives()
+	if robbingKong && t.addedKongRed {
+		redFives++
+	} else if discarder != -1 && !robbingKong && t.activeDiscardRed {
+		redFives++
+	}
+
+	var uraDora []Tile
+	if player.riichi != RiichiNone {
+		uraDora = t.doraTiles(t.GetUraDoraIndicators())
+	}
+
+	return WinContext{
+		Winner:          winner,
+		Discarder:       discarder,
+		WinningTile:     winningTile,
+		SeatWind:        player.wind,
+		PrevalentWind:   t.prevalentWind,
+		BonusTiles:      bonusTiles,
+		ReplacementTile: discarder == -1 && t.receivedReplacement,
+		RobbingKong:     robbingKong,
+		LastTile:        t.wall.IsExhausted(),
+		LastOfKind:      t.visibleCopies(winningTile, discarder, robbingKong) == 3,
+		Dealer:          t.GetDealerIndex(),
+		Seats:           t.seatsInHand(),
+		Repeats:         t.repeats,
+		Riichi:          player.riichi,
+		Ippatsu:         player.ippatsu,
+		FirstDraw:       discarder == -1 && !t.interrupted && len(player.discards) == 0,
+		Dora:            t.doraTiles(t.GetDoraIndicators()),
+		UraDora:         uraDora,
+		RedFives:        redFives,
+	}
+}
+
+// Number of tiles of the given kind that are visible in discards or exposed sets, not counting the winning tile itself.
+func (t *Table) visibleCopies(tile Tile, discarder int, robbingKong bool) int {
+	visible := 0
+	for _, p := range t.players {
+		for _, d := range p.discards {
+			if d.Tile == tile && d.ClaimedBy == -1 {
+				visible++
+			}
+		}
+		for _, c := range p.exposed.combinations {
+			switch comb := c.(type) {
+			case Chow:
+				if tile >= comb.FirstTile && tile <= comb.FirstTile+2 && tile.IsSuit() && tile/10 == comb.FirstTile/10 {
+					visible++
+				}
+			case Pung:
+				if comb.Tile == tile {
+					visible += 3
+				}
+			case Kong:
+				if comb.Tile == tile {
+					visible += 4
+				}
+			}
+		}
+	}
+	if discarder != -1 && !robbingKong {
+		// the winning discard is already part of the discards
+		visible--
+	}
+	return visible
+}
+
+// The tiles the indicators point at.
+func (t *Table) doraTiles(indicators []Tile) []Tile {
+	dora := make([]Tile, len(indicators))
+	for i, indicator := range indicators {
+		dora[i] = t.rules.Tiles.doraFromIndicator(indicator)
+	}
+	return dora
+}
+
+// Score all wins of the round and let the players pay each other.
+func (t *Table) settleRound() {
+	result := &RoundResult{
+		Wins:     make([]Win, 0, len(t.wins)),
+		Abortive: t.abortive,
+	}
+
+	for i, win := range t.wins {
+		win.Score = t.rules.Scorer.Score(win.Hand, win.Context)
+		win.Payments = t.rules.Scorer.Payments(win.Score, win.Context)
+		for s, amount := range win.Payments {
+			t.players[s].score += amount
+		}
+		if i == 0 {
+			// the first winner in turn order collects the deposits
+			win.Deposits = t.deposits
+			t.players[win.Context.Winner].score += t.deposits
+			t.deposits = 0
+		}
+		result.Wins = append(result.Wins, win)
+	}
+
+	// the wall can also run out after some players won and left, then the players still in the hand settle as in a draw
+	inHand := t.seatsInHand()
+	leftHand := len(inHand) < len(t.players)
+	if !t.abortive && (len(t.wins) == 0 || (leftHand && len(inHand) > 1)) {
+		for _, s := range inHand {
+			if t.players[s].isTenpai(t.rules) {
+				result.Tenpai = append(result.Tenpai, s)
+			}
+		}
+
+		if settler, has := t.rules.Scorer.(DrawSettler); has {
+			result.Payments = settler.DrawPayments(result.Tenpai, inHand)
+			for s, amount := range result.Payments {
+				t.players[s].score += amount
+			}
+		}
+	}
+
+	t.wins = nil
+	t.abortive = false
+	t.lastRound = result
+}
+
+// Seats to the right, across and to the left, that the tiles are passed to in the Charleston.
+var charlestonDirections = []int{1, 2, 3}
+
+// Every player passes the chosen tiles to the player in the direction of the current pass.
+func (t *Table) playersPassTiles(passes map[int]PassTiles) {
+	direction := charlestonDirections[t.charlestonPasses]
+	for s, pass := range passes {
+		for _, tile := range pass.Tiles {
+			t.players[s].concealed.remove(tile)
+		}
+	}
+	for s, pass := range passes {
+		receiver := t.players[(s+direction)%len(t.players)]
+		for _, tile := range pass.Tiles {
+			receiver.concealed.add(tile)
+		}
+	}
+	t.charlestonPasses++
+}
+
 func (t *Table) makePlayerActive(player int) {
 	t.activePlayer = player
 }
@@ -141,24 +780,45 @@ func (t *Table) activePlayerDeclaresConcealedKong(tile Tile) {
 		activePlayer.received = nil
 	}
 
+	activePlayer.exposedRedFives += activePlayer.giveUpFives(tile, 4)
 	activePlayer.concealed.removeAll(tile)
 	activePlayer.exposed.add(Kong{
 		Tile:      tile,
-		Concealed: false,
+		Concealed: true,
 	})
+
+	t.interruptTurnOrder()
+	t.revealKongIndicator()
 }
 
-func (t *Table) activePlayerAddsToExposedPung() {
+// The active player takes the received tile to add it to an exposed pung.
+// The kong is only formed once the other players had the chance to rob it.
+func (t *Table) activePlayerAnnouncesAddedKong() {
 	activePlayer := t.GetActivePlayer()
 
-	activePlayer.exposed.replace(
-		Pung{Tile: *activePlayer.received},
-		Kong{Tile: *activePlayer.received, Concealed: false},
-	)
-
+	t.addedKongRed = activePlayer.giveUpFives(*activePlayer.received, 1) > 0
+	t.addedKongTile = activePlayer.received
 	activePlayer.received = nil
 }
 
+func (t *Table) activePlayerAddsToExposedPung() {
+	if t.addedKongTile != nil {
+		activePlayer := t.GetActivePlayer()
+		activePlayer.exposed.replace(
+			Pung{Tile: *t.addedKongTile},
+			Kong{Tile: *t.addedKongTile, Concealed: false},
+		)
+		if t.addedKongRed {
+			activePlayer.exposedRedFives++
+		}
+		t.addedKongTile = nil
+		t.addedKongRed = false
+
+		t.interruptTurnOrder()
+		t.revealKongIndicator()
+	}
+}
+
 func (t *Table) activePlayerDiscards(tile Tile) {
 	activePlayer := t.GetActivePlayer()
 
@@ -167,21 +827,100 @@ func (t *Table) activePlayerDiscards(tile Tile) {
 		activePlayer.received = nil
 	}
 
+	t.activeDiscardRed = activePlayer.giveUpFives(tile, 1) > 0
 	activePlayer.concealed.remove(tile)
+	activePlayer.discards = append(activePlayer.discards, DiscardedTile{Tile: tile, ClaimedBy: -1})
+	activePlayer.ippatsu = false
+	if activePlayer.riichi == RiichiNone {
+		// after declaring riichi, letting a winning tile pass counts for the rest of the round
+		activePlayer.passedOnWin = false
+	}
 
 	t.activeDiscard = &tile
+	t.riichiDiscard = false
+}
+
+// Whether the active player may declare riichi on this turn.
+// The hand has to be concealed, the player has to be able to pay the deposit, and there have to be enough tiles left for everyone to draw once more.
+func (t *Table) activePlayerCanDeclareRiichi() bool {
+	activePlayer := t.GetActivePlayer()
+
+	return t.rules.Riichi &&
+		activePlayer.riichi == RiichiNone &&
+		activePlayer.hasConcealedHand() &&
+		activePlayer.score >= t.rules.RiichiDeposit &&
+		t.wall.LiveSize() >= len(t.players)
+}
+
+// The active player discards the tile to declare riichi. The deposit is only paid once nobody wins on the discard.
+func (t *Table) activePlayerDeclaresRiichi(tile Tile) {
+	activePlayer := t.GetActivePlayer()
+
+	status := RiichiDeclared
+	if len(activePlayer.discards) == 0 && !t.interrupted {
+		status = RiichiDoubleDeclared
+	}
+
+	t.activePlayerDiscards(tile)
+
+	activePlayer.riichi = status
+	activePlayer.ippatsu = true
+	t.riichiDiscard = true
+}
+
+// Nobody won on the riichi discard, so the declaring player puts the deposit on the table.
+func (t *Table) activePlayerPaysRiichiDeposit() {
+	if t.riichiDiscard {
+		t.GetActivePlayer().score -= t.rules.RiichiDeposit
+		t.deposits += t.rules.RiichiDeposit
+		t.riichiDiscard = false
+	}
+}
+
+// The player claims the active discard for a combination and becomes the active player.
+func (t *Table) playerClaimsDiscard(player int) {
+	discarder := t.GetActivePlayer()
+	if len(discarder.discards) > 0 {
+		discarder.discards[len(discarder.discards)-1].ClaimedBy = player
+	}
+
+	t.interruptTurnOrder()
+	t.makePlayerActive(player)
+}
+
+// Players that could have won on the tile but did not claim it are furiten until their next discard.
+func (t *Table) playersPassOnWinningTile(tile Tile, actions map[int]state.Action) {
+	if !t.rules.Furiten {
+		return
+	}
+
+	for s, action := range actions {
+		if _, isMahjong := action.(DeclareMahjong); isMahjong {
+			continue
+		}
+		if t.rules.isWinningHand(t.players[s].concealed, t.players[s].exposed, tile) {
+			t.players[s].passedOnWin = true
+		}
+	}
 }
 
 func (t *Table) activePlayerTakesDiscarded() {
 	if t.activeDiscard != nil {
 		t.GetActivePlayer().discarded.add(*t.activeDiscard)
 		t.activeDiscard = nil
+		t.activeDiscardRed = false
 	}
 }
 
 func (t *Table) activePlayerTakesChow(tile Tile) {
 	if t.activeDiscard != nil {
 		activePlayer := t.GetActivePlayer()
+		for _, chowTile := range []Tile{tile, tile + 1, tile + 2} {
+			if chowTile != *t.activeDiscard {
+				activePlayer.exposedRedFives += activePlayer.giveUpFives(chowTile, 1)
+			}
+		}
+		t.activeDiscardTakenIntoCombination()
 		activePlayer.concealed.add(*t.activeDiscard)
 		activePlayer.concealed.remove(tile)
 		activePlayer.concealed.remove(tile + 1)
@@ -194,6 +933,8 @@ func (t *Table) activePlayerTakesChow(tile Tile) {
 func (t *Table) activePlayerTakesPung() {
 	if t.activeDiscard != nil {
 		activePlayer := t.GetActivePlayer()
+		activePlayer.exposedRedFives += activePlayer.giveUpFives(*t.activeDiscard, 2)
+		t.activeDiscardTakenIntoCombination()
 		activePlayer.concealed.remove(*t.activeDiscard)
 		activePlayer.concealed.remove(*t.activeDiscard)
 		activePlayer.exposed.add(Pung{Tile: *t.activeDiscard})
@@ -204,8 +945,19 @@ func (t *Table) activePlayerTakesPung() {
 func (t *Table) activePlayerTakesKong() {
 	if t.activeDiscard != nil {
 		activePlayer := t.GetActivePlayer()
+		activePlayer.exposedRedFives += activePlayer.giveUpFives(*t.activeDiscard, 3)
+		t.activeDiscardTakenIntoCombination()
 		activePlayer.concealed.removeAll(*t.activeDiscard)
 		activePlayer.exposed.add(Kong{Tile: *t.activeDiscard, Concealed: false})
 		t.activeDiscard = nil
+		t.revealKongIndicator()
+	}
+}
+
+// The active player takes the active discard into an exposed combination, along with its red five if it is one.
+func (t *Table) activeDiscardTakenIntoCombination() {
+	if t.activeDiscardRed {
+		t.GetActivePlayer().exposedRedFives++
+		t.activeDiscardRed = false
 	}
 }
@@ -0,0 +1,347 @@
+package mahjong
+
+import (
+	"github.com/roelofruis/mahjong-learn/state"
+	"testing"
+)
+
+func TestDealerRetention(t *testing.T) {
+	table := newTable(ClassicalRuleset(), 0)
+
+	table.lastRound = &RoundResult{Wins: []Win{{Context: WinContext{Winner: 0}}}}
+	if !table.advanceHand() || table.GetDealerIndex() != 0 || table.GetRepeatCounter() != 1 {
+		t.Errorf("expected the dealer to keep the seat after winning")
+	}
+
+	table.lastRound = &RoundResult{Wins: []Win{{Context: WinContext{Winner: 2}}}}
+	if !table.advanceHand() || table.GetDealerIndex() != 1 || table.GetRepeatCounter() != 0 {
+		t.Errorf("expected the dealer seat to pass on after another player won")
+	}
+
+	table.lastRound = &RoundResult{}
+	if !table.advanceHand() || table.GetDealerIndex() != 2 || table.GetRepeatCounter() != 1 {
+		t.Errorf("expected the dealer seat to pass on after a draw, counting a repeat")
+	}
+
+	table.dealer = 3
+	table.prevalentWind = North
+	if table.advanceHand() {
+		t.Errorf("expected the game to end after the last hand of the north round")
+	}
+}
+
+func TestMatchLength(t *testing.T) {
+	rules := ClassicalRuleset()
+	rules.Length = MatchSingleHand
+	table := newTable(rules, 0)
+	table.lastRound = &RoundResult{Wins: []Win{{Context: WinContext{Winner: 0}}}}
+	if table.advanceHand() {
+		t.Errorf("expected the game to end after a single hand")
+	}
+
+	rules.Length = MatchEastOnly
+	table = newTable(rules, 0)
+	table.dealer = 3
+	table.lastRound = &RoundResult{}
+	if table.advanceHand() {
+		t.Errorf("expected the game to end after the last hand of the east round")
+	}
+
+	rules.BustEnds = true
+	table = newTable(rules, 0)
+	table.players[2].score = -100
+	table.lastRound = &RoundResult{}
+	if table.advanceHand() {
+		t.Errorf("expected the game to end when a player is bust")
+	}
+}
+
+func TestOvertime(t *testing.T) {
+	table := newTable(RiichiRuleset(), 0)
+	table.dealer = 3
+	table.prevalentWind = South
+	table.lastRound = &RoundResult{}
+	if !table.advanceHand() || table.GetPrevalentWind() != West {
+		t.Errorf("expected the game to go into the west round when nobody reached the target")
+	}
+
+	table.lastRound = &RoundResult{}
+	if !table.advanceHand() {
+		t.Errorf("expected the game to go on in overtime until someone reaches the target")
+	}
+
+	table.players[1].score = 30000
+	table.lastRound = &RoundResult{}
+	if table.advanceHand() {
+		t.Errorf("expected the game to end in overtime once someone reached the target")
+	}
+
+	table = newTable(RiichiRuleset(), 0)
+	table.dealer = 3
+	table.prevalentWind = South
+	table.players[0].score = 31000
+	table.lastRound = &RoundResult{}
+	if table.advanceHand() {
+		t.Errorf("expected the game to end after the south round when someone reached the target")
+	}
+}
+
+func TestDealsHandSize(t *testing.T) {
+	game, err := NewGame(TaiwaneseRuleset(), &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for seat := 0; seat < 4; seat++ {
+		if size := game.Table.GetPlayerByIndex(seat).GetConcealedTiles().Size(); size != 16 {
+			t.Errorf("expected player [%d] to be dealt [16] tiles, got [%d]", seat, size)
+		}
+	}
+}
+
+func TestSanmaDeal(t *testing.T) {
+	game, err := NewGame(SanmaRuleset(), &state.DebugTransitioner{IntermediateTransitionLimit: 10, ActionLimit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if n := game.Table.GetPlayerCount(); n != 3 {
+		t.Fatalf("expected [3] players, got [%d]", n)
+	}
+	for seat := 0; seat < 3; seat++ {
+		player := game.Table.GetPlayerByIndex(seat)
+		if player.GetWind() != Wind(seat) {
+			t.Errorf("expected player [%d] to have wind [%d], got [%d]", seat, seat, player.GetWind())
+		}
+		if n := player.GetConcealedTiles().NumOf(NorthWind); n != 0 {
+			t.Errorf("expected player [%d] to have set aside all north winds, [%d] are concealed", seat, n)
+		}
+		if player.GetConcealedTiles().NumOf(Characters5) != 0 {
+			t.Errorf("expected player [%d] to hold no five of characters", seat)
+		}
+	}
+}
+
+func TestMissingSuit(t *testing.T) {
+	table := newTable(SichuanRuleset(), 0)
+
+	player := table.players[1]
+	player.missingSuit = Characters1
+	player.concealed = tilesOf(Bamboo1, Bamboo2, Bamboo3, Circles5, Circles5, Circles5, Circles7, Circles8, Circles9, Bamboo6, Bamboo6, Bamboo8, Characters2)
+	received := Bamboo7
+	player.received = &received
+
+	actions := player.getTileReceivedActions(table.rules, false, false)
+	if len(actions) != 1 || !hasAction(actions, Discard{Tile: Characters2}) {
+		t.Errorf("expected the player to have to discard the tile of the missing suit, got %+v", actions)
+	}
+	if table.canDeclareMahjong(1, Bamboo7, 0) {
+		t.Errorf("expected the player not to be able to win while holding the missing suit")
+	}
+	if hasAction(player.getTileDiscardedActions(Characters2, true, false, table.rules), DeclarePung{}) {
+		t.Errorf("expected the player not to be able to claim the missing suit")
+	}
+
+	player.concealed.remove(Characters2)
+	player.concealed.add(Bamboo6)
+	if !table.canDeclareMahjong(1, Bamboo7, 0) {
+		t.Errorf("expected the player to be able to win without tiles of the missing suit")
+	}
+}
+
+func TestPlayOnAfterWin(t *testing.T) {
+	table := newTable(SichuanRuleset(), 0)
+	tiles := table.GetWall().Size()
+
+	discard, _ := table.wall.draw()
+	table.activeDiscard = &discard
+	table.players[0].discards = []DiscardedTile{{Tile: discard, ClaimedBy: -1}}
+	table.wins = []Win{
+		{Context: WinContext{Winner: 1, Discarder: 0, WinningTile: discard}},
+		{Context: WinContext{Winner: 2, Discarder: 0, WinningTile: discard}},
+	}
+
+	if !table.winnersLeaveHand() {
+		t.Fatalf("expected the remaining players to play on")
+	}
+	if !table.players[1].HasWon() || !table.players[2].HasWon() || table.activeDiscard != nil {
+		t.Errorf("expected both winners to leave the hand")
+	}
+	if table.players[1].concealed.NumOf(discard) != 1 || table.players[2].concealed.NumOf(discard) != 0 {
+		t.Errorf("expected only the first winner to take the discard into their hand")
+	}
+	if err := checkTileCount(*table, tiles); err != nil {
+		t.Errorf("expected no tiles to be created by a multiple win: %s", err.Error())
+	}
+	if table.players[0].discards[0].ClaimedBy != 1 {
+		t.Errorf("expected the discard to count as claimed by the first winner, got [%d]", table.players[0].discards[0].ClaimedBy)
+	}
+	if table.GetActivePlayerIndex() != 3 {
+		t.Errorf("expected the player after the last winner to play on, got [%d]", table.GetActivePlayerIndex())
+	}
+	if indices := table.GetReactingPlayerIndices(); len(indices) != 1 || indices[0] != 0 {
+		t.Errorf("expected only the player still in the hand to react, got %v", indices)
+	}
+
+	table.wins = append(table.wins, Win{Context: WinContext{Winner: 3, Discarder: -1}})
+	if table.winnersLeaveHand() {
+		t.Errorf("expected the hand to end when a single player is left")
+	}
+}
+
+func TestCharleston(t *testing.T) {
+	card, err := LoadCard("cards/sample.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	table := newTable(AmericanRuleset(card), 0)
+	for s, p := range table.players {
+		p.concealed = tilesOf(Joker, Bamboo1+Tile(s), Bamboo1+Tile(s), Bamboo1+Tile(s))
+	}
+
+	if actions := table.players[0].getCharlestonActions(); len(actions) != 1 {
+		t.Errorf("expected jokers not to be passed, got %+v", actions)
+	}
+
+	passes := make(map[int]PassTiles)
+	for s := range table.players {
+		passes[s] = PassTiles{Tiles: [3]Tile{Bamboo1 + Tile(s), Bamboo1 + Tile(s), Bamboo1 + Tile(s)}}
+	}
+	table.playersPassTiles(passes)
+	if table.players[1].concealed.NumOf(Bamboo1) != 3 || table.players[0].concealed.NumOf(Bamboo4) != 3 {
+		t.Errorf("expected the first pass to go to the right")
+	}
+
+	table.playersPassTiles(map[int]PassTiles{0: {Tiles: [3]Tile{Bamboo4, Bamboo4, Bamboo4}}})
+	if table.players[2].concealed.NumOf(Bamboo4) != 3 {
+		t.Errorf("expected the second pass to go across")
+	}
+}
+
+func TestResolveWinningClaims(t *testing.T) {
+	cases := []struct {
+		policy   MultipleWinPolicy
+		claims   []int
+		winners  int
+		abortive bool
+	}{
+		{policy: MultipleWinsHeadBump, claims: []int{2, 3}, winners: 1},
+		{policy: MultipleWinsAllowed, claims: []int{1, 2, 3}, winners: 3},
+		{policy: MultipleWinsAbortOnTriple, claims: []int{1, 3}, winners: 2},
+		{policy: MultipleWinsAbortOnTriple, claims: []int{1, 2, 3}, winners: 0, abortive: true},
+	}
+
+	for _, c := range cases {
+		rules := ClassicalRuleset()
+		rules.MultipleWins = c.policy
+		table := newTable(rules, 0)
+
+		winners := table.resolveWinningClaims(c.claims)
+		if len(winners) != c.winners || table.abortive != c.abortive {
+			t.Errorf("policy [%d] with claims %v: expected [%d] winners and abortive [%t], got %v and [%t]", c.policy, c.claims, c.winners, c.abortive, winners, table.abortive)
+		}
+		if c.winners == 1 && winners[0] != c.claims[0] {
+			t.Errorf("expected the first claim in turn order to win, got [%d]", winners[0])
+		}
+	}
+}
+
+func TestFuriten(t *testing.T) {
+	rules := ClassicalRuleset()
+	rules.Furiten = true
+	table := newTable(rules, 0)
+
+	player := table.players[1]
+	player.concealed = tilesOf(Bamboo1, Bamboo2, Bamboo3, Circles5, Circles5, Circles5, Characters7, Characters8, Characters9, RedDragon, RedDragon, Circles3, Circles4)
+
+	if table.IsFuriten(1) || !table.canDeclareMahjong(1, Circles2, 0) {
+		t.Errorf("expected the player to be able to win on a discard")
+	}
+
+	player.discards = append(player.discards, DiscardedTile{Tile: Circles5, ClaimedBy: 1}, DiscardedTile{Tile: Circles2, ClaimedBy: 3})
+	if !table.IsFuriten(1) || table.canDeclareMahjong(1, Circles2, 0) {
+		t.Errorf("expected the player to be furiten after discarding one of the waits, even if it was claimed")
+	}
+	if !table.canDeclareMahjong(1, Circles2, -1) {
+		t.Errorf("expected furiten not to prevent winning on a self drawn tile")
+	}
+	table.rules.Furiten = false
+	if !table.canDeclareMahjong(1, Circles2, 0) {
+		t.Errorf("expected furiten to be ignored if the ruleset does not use it")
+	}
+
+	player.discards = nil
+	player.passedOnWin = true
+	if !player.isFuriten(rules) {
+		t.Errorf("expected the player to be furiten after letting a winning tile pass")
+	}
+}
+
+func TestRulesetClaims(t *testing.T) {
+	player := newPlayer(South)
+	player.concealed = tilesOf(Bamboo2, Bamboo3, Circles5, Circles5, Characters7, Characters8, Characters9, RedDragon, RedDragon, RedDragon, WestWind, WestWind, WestWind)
+
+	rules := ClassicalRuleset()
+	if !hasAction(player.getTileDiscardedActions(Bamboo1, true, false, rules), DeclareChow{Tile: Bamboo1}) {
+		t.Errorf("expected the next player to be able to claim a chow")
+	}
+	if rules.claimPriority(DeclarePung{}) <= rules.claimPriority(DeclareChow{}) || rules.claimPriority(DeclareMahjong{}) <= rules.claimPriority(DeclareKong{}) {
+		t.Errorf("expected claims to be prioritised in the order of the ruleset")
+	}
+
+	rules.Claims = []Claim{ClaimMahjong, ClaimKong, ClaimPung}
+	if hasAction(player.getTileDiscardedActions(Bamboo1, true, false, rules), DeclareChow{Tile: Bamboo1}) {
+		t.Errorf("expected no chow claims if the ruleset leaves them out")
+	}
+	if rules.claimPriority(DeclareChow{}) != 0 {
+		t.Errorf("expected a chow not to be a valid claim if the ruleset leaves it out")
+	}
+}
+
+func TestHouseRules(t *testing.T) {
+	player := newPlayer(South)
+	player.concealed = tilesOf(Bamboo2, Bamboo3, Circles5, Circles5, Characters7, Characters8, Characters9, RedDragon, RedDragon, WestWind, WestWind, WestWind, WestWind)
+
+	rules := ClassicalRuleset()
+	rules.House.NoChowClaims = true
+	if hasAction(player.getTileDiscardedActions(Bamboo1, true, false, rules), DeclareChow{Tile: Bamboo1}) {
+		t.Errorf("expected no chow claims when they are switched off")
+	}
+
+	rules.House.NoClaimInterrupts = true
+	if hasAction(player.getTileDiscardedActions(RedDragon, false, false, rules), DeclarePung{}) {
+		t.Errorf("expected only the next player to claim a set without claim interrupts")
+	}
+	if !hasAction(player.getTileDiscardedActions(RedDragon, true, false, rules), DeclarePung{}) {
+		t.Errorf("expected the next player to be able to claim a pung without claim interrupts")
+	}
+
+	if !hasAction(player.getDiscardAfterCombinationActions(rules), DeclareConcealedKong{Tile: WestWind}) {
+		t.Errorf("expected a concealed kong to be possible")
+	}
+	rules.House.NoConcealedKongs = true
+	if hasAction(player.getDiscardAfterCombinationActions(rules), DeclareConcealedKong{Tile: WestWind}) {
+		t.Errorf("expected no concealed kongs when they are switched off")
+	}
+
+	rules.House.NoBonusTiles = true
+	if newTable(rules, 0).wall.Size() != 136 {
+		t.Errorf("expected the bonus tiles to be left out of the wall")
+	}
+
+	if err := rules.House.Enable("no-pung"); err != nil || !rules.House.NoPungClaims {
+		t.Errorf("expected pung claims to be switched off by name")
+	}
+	if len(rules.House.Names()) != 5 {
+		t.Errorf("expected five house rules to be switched on, got %v", rules.House.Names())
+	}
+}
+
+func hasAction(actions []state.Action, action state.Action) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
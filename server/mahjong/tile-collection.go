@@ -1,7 +1,6 @@
 package mahjong
 
 import (
-	"math/rand"
 	"sort"
 )
 
@@ -29,6 +28,14 @@ func newMahjongSet() *TileCollection {
 
 // Getters
 
+func (t *TileCollection) copy() *TileCollection {
+	tiles := make(map[Tile]uint8, len(t.tiles))
+	for tile, count := range t.tiles {
+		tiles[tile] = count
+	}
+	return &TileCollection{tiles: tiles}
+}
+
 func (t *TileCollection) NumOf(tile Tile) int {
 	count, has := t.tiles[tile]
 	if !has {
@@ -46,18 +53,48 @@ func (t *TileCollection) Size() int {
 	return count
 }
 
+// The count of every tile in the collection, most frequent first and in tile order among equal counts.
 func (t *TileCollection) OrderedCounts() []TileCount {
-	i := 0
-	ordered := make([]TileCount, len(t.tiles))
+	ordered := make([]TileCount, 0, len(t.tiles))
 	for tile, count := range t.tiles {
-		ordered[i] = TileCount{tile, count}
+		if count > 0 {
+			ordered = append(ordered, TileCount{tile, count})
+		}
 	}
 	sort.Slice(ordered, func(i, j int) bool {
-		return ordered[i].Count > ordered[j].Count
+		if ordered[i].Count != ordered[j].Count {
+			return ordered[i].Count > ordered[j].Count
+		}
+		return ordered[i].Tile < ordered[j].Tile
 	})
 	return ordered
 }
 
+// All tiles in the collection as a list, in tile order.
+func (t *TileCollection) list() []Tile {
+	tiles := make([]Tile, 0, t.Size())
+	for _, tile := range t.withAtLeast(1) {
+		for i := t.tiles[tile]; i > 0; i-- {
+			tiles = append(tiles, tile)
+		}
+	}
+	return tiles
+}
+
+// Tiles that occur at least n times, in tile order.
+func (t *TileCollection) withAtLeast(n uint8) []Tile {
+	tiles := make([]Tile, 0)
+	for tile, count := range t.tiles {
+		if count >= n {
+			tiles = append(tiles, tile)
+		}
+	}
+	sort.Slice(tiles, func(i, j int) bool {
+		return tiles[i] < tiles[j]
+	})
+	return tiles
+}
+
 type TileCount struct {
 	Tile Tile
 	Count uint8
@@ -89,21 +126,6 @@ func (t *TileCollection) add(tile Tile) {
 	t.tiles[tile]++
 }
 
-func (t *TileCollection) removeRandom() Tile {
-	var tileList = make([]Tile, 0)
-	for k, v := range t.tiles {
-		for i := v; i > 0; i-- {
-			tileList = append(tileList, k)
-		}
-	}
-	pos := rand.Intn(len(tileList))
-	picked := tileList[pos]
-
-	t.tiles[picked]--
-
-	return picked
-}
-
 type CombinationCollection struct {
 	combinations []Combination
 }
@@ -114,6 +136,14 @@ func newCombinationCollection() *CombinationCollection {
 
 // Getters
 
+// A copy of the combinations, in combination order. The collection itself is left as it is.
+func (c *CombinationCollection) Combinations() []Combination {
+	combinations := append(make([]Combination, 0, len(c.combinations)), c.combinations...)
+	sort.Sort(ByCombinationOrder(combinations))
+
+	return combinations
+}
+
 func (c CombinationCollection) Contains(check Combination) bool {
 	for _, comb := range c.combinations {
 		if comb == check {
@@ -123,6 +153,17 @@ func (c CombinationCollection) Contains(check Combination) bool {
 	return false
 }
 
+// Number of times the combination is in the collection.
+func (c CombinationCollection) Count(check Combination) int {
+	n := 0
+	for _, comb := range c.combinations {
+		if comb == check {
+			n++
+		}
+	}
+	return n
+}
+
 // State modifiers
 
 func (c *CombinationCollection) empty() {
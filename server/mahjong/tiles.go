@@ -45,6 +45,7 @@ const (
 	SeasonSummer        Tile = 61
 	SeasonAutumn        Tile = 62
 	SeasonWinter        Tile = 63
+	Joker               Tile = 70
 )
 
 func (t Tile) IsBamboo() bool {
@@ -63,6 +64,11 @@ func (t Tile) IsSuit() bool {
 	return t < 30
 }
 
+// The first tile of the suit the tile belongs to.
+func (t Tile) Suit() Tile {
+	return (t/10)*10 + 1
+}
+
 func (t Tile) IsDragon() bool {
 	return t >= 30 && t <= 32
 }
@@ -71,8 +77,30 @@ func (t Tile) IsWind() bool {
 	return t >= 40 && t <= 43
 }
 
+func (t Tile) IsHonor() bool {
+	return t.IsDragon() || t.IsWind()
+}
+
+func (t Tile) IsTerminal() bool {
+	return t.IsSuit() && (t%10 == 1 || t%10 == 9)
+}
+
+func (t Tile) IsTerminalOrHonor() bool {
+	return t.IsTerminal() || t.IsHonor()
+}
+
+// Suit tiles from 2 to 8.
+func (t Tile) IsSimple() bool {
+	return t.IsSuit() && !t.IsTerminal()
+}
+
 func (t Tile) IsBonusTile() bool {
-	return t >= 50
+	return t >= 50 && t < Joker
+}
+
+// Jokers stand in for any tile in a group of three or more identical tiles.
+func (t Tile) IsJoker() bool {
+	return t == Joker
 }
 
 func(t Tile) NextInSuit() *Tile {
@@ -85,4 +113,37 @@ func(t Tile) NextInSuit() *Tile {
 	suit := (t / 10) * 10
 	tile := suit + (t % 10) + 1
 	return &tile
-}
\ No newline at end of file
+}
+
+// All kinds of tiles that can be part of a hand, so excluding bonus tiles.
+func allTileKinds() []Tile {
+	tiles := make([]Tile, 0, 34)
+	for _, suit := range []Tile{Bamboo1, Circles1, Characters1} {
+		for i := Tile(0); i < 9; i++ {
+			tiles = append(tiles, suit+i)
+		}
+	}
+	return append(tiles, RedDragon, GreenDragon, WhiteDragon, EastWind, SouthWind, WestWind, NorthWind)
+}
+
+// The tile that counts as dora when this tile is the indicator: the next tile of the suit, wind or dragon, wrapping around at the end.
+func (t Tile) DoraFromIndicator() Tile {
+	switch {
+	case t.IsSuit():
+		if t%10 == 9 {
+			return t - 8
+		}
+		return t + 1
+	case t == NorthWind:
+		return EastWind
+	case t.IsWind():
+		return t + 1
+	case t == WhiteDragon:
+		return GreenDragon
+	case t == GreenDragon:
+		return RedDragon
+	case t == RedDragon:
+		return WhiteDragon
+	}
+	return t
+}
@@ -49,6 +49,7 @@ var tileNames = map[mahjong.Tile]string{
 	mahjong.SeasonSummer:        "Summer (season)",
 	mahjong.SeasonAutumn:        "Autumn (season)",
 	mahjong.SeasonWinter:        "Winter (season)",
+	mahjong.Joker:               "Joker",
 }
 
 var windNames = map[mahjong.Wind]string{
@@ -58,6 +59,20 @@ var windNames = map[mahjong.Wind]string{
 	mahjong.North: "North",
 }
 
+var riichiNames = map[mahjong.RiichiStatus]string{
+	mahjong.RiichiNone:           "none",
+	mahjong.RiichiDeclared:       "riichi",
+	mahjong.RiichiDoubleDeclared: "double riichi",
+}
+
+// Names of the suits by their first tile.
+var suitNames = map[mahjong.Tile]string{
+	0:                   "none",
+	mahjong.Bamboo1:     "Bamboo",
+	mahjong.Circles1:    "Circles",
+	mahjong.Characters1: "Characters",
+}
+
 func tileName(t *mahjong.Tile) string {
 	if t == nil {
 		return "none"
@@ -65,7 +80,8 @@ func tileName(t *mahjong.Tile) string {
 	return tileNames[*t]
 }
 
-func combinationNames(combinations []mahjong.Combination) []string {
+// Describe the combinations. If hideConcealed is set, the tiles of concealed kongs are left out.
+func combinationNames(combinations []mahjong.Combination, hideConcealed bool) []string {
 	descriptions := make([]string, len(combinations))
 	for i, combi := range combinations {
 		switch c := combi.(type) {
@@ -79,7 +95,13 @@ func combinationNames(combinations []mahjong.Combination) []string {
 			descriptions[i] = fmt.Sprintf("Pung %s", tileNames[c.Tile])
 
 		case mahjong.Kong:
-			descriptions[i] = fmt.Sprintf("Kong %s", tileNames[c.Tile])
+			if c.Concealed && hideConcealed {
+				descriptions[i] = "Concealed kong"
+			} else if c.Concealed {
+				descriptions[i] = fmt.Sprintf("Concealed kong %s", tileNames[c.Tile])
+			} else {
+				descriptions[i] = fmt.Sprintf("Kong %s", tileNames[c.Tile])
+			}
 
 		default:
 			// This should not happen..!
@@ -98,6 +120,26 @@ func tileCollectionNames(t *mahjong.TileCollection) []string {
 	return descriptions
 }
 
+func tileListNames(tiles []mahjong.Tile) []string {
+	descriptions := make([]string, len(tiles))
+	for i, t := range tiles {
+		descriptions[i] = tileNames[t]
+	}
+	return descriptions
+}
+
+func discardHistoryNames(discards []mahjong.DiscardedTile) []string {
+	descriptions := make([]string, len(discards))
+	for i, d := range discards {
+		if d.ClaimedBy == -1 {
+			descriptions[i] = tileNames[d.Tile]
+		} else {
+			descriptions[i] = fmt.Sprintf("%s (claimed by %d)", tileNames[d.Tile], d.ClaimedBy)
+		}
+	}
+	return descriptions
+}
+
 func actionNames(action state.Action) string {
 	switch a := action.(type) {
 	case mahjong.Discard:
@@ -116,8 +158,29 @@ func actionNames(action state.Action) string {
 		return "Declare a kong"
 	case mahjong.DeclareMahjong:
 		return "Declare mahjong"
+	case mahjong.DeclareRiichi:
+		return fmt.Sprintf("Declare riichi and discard a %s", tileNames[a.Tile])
+	case mahjong.PassTiles:
+		return fmt.Sprintf("Pass %s, %s and %s", tileNames[a.Tiles[0]], tileNames[a.Tiles[1]], tileNames[a.Tiles[2]])
+	case mahjong.DeclareMissingSuit:
+		return fmt.Sprintf("Declare %s as missing suit", suitNames[a.Suit])
 
 	default:
 		panic(fmt.Errorf("unknown action %+v", a))
 	}
 }
+
+func scoreItemNames(items []mahjong.ScoreItem) []string {
+	descriptions := make([]string, len(items))
+	for i, item := range items {
+		switch {
+		case item.Points != 0 && item.Doubles != 0:
+			descriptions[i] = fmt.Sprintf("%s: %d points, %d doubles", item.Name, item.Points, item.Doubles)
+		case item.Doubles != 0:
+			descriptions[i] = fmt.Sprintf("%s: %d doubles", item.Name, item.Doubles)
+		default:
+			descriptions[i] = fmt.Sprintf("%s: %d points", item.Name, item.Points)
+		}
+	}
+	return descriptions
+}
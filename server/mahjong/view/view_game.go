@@ -13,24 +13,63 @@ type GamePlayerView struct {
 	Concealed []string       `json:"concealed"`
 	Exposed   []string       `json:"exposed"`
 	Discarded []string       `json:"discarded"`
+	History   []string       `json:"discard_history"`
+	Furiten   bool           `json:"furiten"`
+	Riichi    string         `json:"riichi"`
+	RedFives  int            `json:"red_fives"`
+	// Suit the player has to get rid of and whether the player won and left the hand, in rulesets that play on after a win.
+	MissingSuit string `json:"missing_suit"`
+	Won         bool   `json:"won"`
 }
 
 type GameView struct {
 	HasEnded      bool                   `json:"has_ended"`
+	Ruleset       string                 `json:"ruleset"`
+	HouseRules    []string               `json:"house_rules"`
+	Seed          int64                  `json:"seed"`
 	StateName     string                 `json:"state_name"`
 	PrevalentWind string                 `json:"prevalent_wind"`
+	Dealer        int                    `json:"dealer"`
+	HandNumber    int                    `json:"hand_number"`
+	HandsPlayed   int                    `json:"hands_played"`
+	Repeats       int                    `json:"repeats"`
+	Deposits      int                    `json:"riichi_deposits"`
+	Dora          []string               `json:"dora_indicators"`
 	ActivePlayers []int                  `json:"active_players"`
 	ActiveDiscard string                 `json:"active_discard"`
+	AddedKongTile string                 `json:"added_kong_tile"`
 	Players       map[int]GamePlayerView `json:"players"`
+	WallSize      int                    `json:"wall_size"`
 	Wall          []string               `json:"wall"`
+	LastRound     *RoundResultView       `json:"last_round"`
+}
+
+type RoundResultView struct {
+	Wins     []WinView   `json:"wins"`
+	Abortive bool        `json:"abortive"`
+	Tenpai   []int       `json:"tenpai"`
+	Payments map[int]int `json:"payments"`
+}
+
+type WinView struct {
+	Player    int         `json:"player"`
+	Discarder int         `json:"discarder"`
+	Tile      string      `json:"tile"`
+	Concealed []string    `json:"concealed"`
+	Exposed   []string    `json:"exposed"`
+	Score     []string    `json:"score"`
+	Value     int         `json:"value"`
+	Total     int         `json:"total"`
+	Payments  map[int]int `json:"payments"`
+	Deposits  int         `json:"riichi_deposits"`
 }
 
 func ViewGame(game *mahjong.Game) *GameView {
 	table := *game.Table
 
 	var activePlayers []int
-	playerViews := make(map[int]GamePlayerView, 4)
-	for _, player := range []int{0, 1, 2, 3} {
+	playerViews := make(map[int]GamePlayerView, table.GetPlayerCount())
+	for player := 0; player < table.GetPlayerCount(); player++ {
 		actions, has := game.StateMachine.AvailableActions()[player]
 		if !has {
 			actions = make([]state.Action, 0)
@@ -42,12 +81,24 @@ func ViewGame(game *mahjong.Game) *GameView {
 
 	return &GameView{
 		HasEnded:      game.StateMachine.HasTerminated(),
+		Ruleset:       table.GetRuleset().Name,
+		HouseRules:    table.GetRuleset().House.Names(),
+		Seed:          table.GetSeed(),
 		StateName:     game.StateMachine.StateName(),
 		PrevalentWind: windNames[table.GetPrevalentWind()],
+		Dealer:        table.GetDealerIndex(),
+		HandNumber:    table.GetHandNumber(),
+		HandsPlayed:   table.GetHandsPlayed(),
+		Repeats:       table.GetRepeatCounter(),
+		Deposits:      table.GetDeposits(),
+		Dora:          tileListNames(table.GetDoraIndicators()),
 		ActivePlayers: activePlayers,
 		ActiveDiscard: tileName(table.GetActiveDiscard()),
+		AddedKongTile: tileName(table.GetAddedKongTile()),
 		Players:       playerViews,
-		Wall:          tileCollectionNames(table.GetWall()),
+		WallSize:      table.GetWallSize(),
+		Wall:          tileCollectionNames(table.GetWall().Tiles()),
+		LastRound:     describeRoundResult(table.GetLastRoundResult()),
 	}
 }
 
@@ -65,7 +116,44 @@ func describeGamePlayer(g mahjong.Table, actions []state.Action, player int) Gam
 		Wind:      windNames[p.GetWind()],
 		Received:  tileName(p.GetReceivedTile()),
 		Concealed: tileCollectionNames(p.GetConcealedTiles()),
-		Exposed:   combinationNames(p.GetExposedCombinations()),
+		Exposed:   combinationNames(p.GetExposedCombinations(), false),
 		Discarded: tileCollectionNames(p.GetDiscardedTiles()),
+		History:   discardHistoryNames(p.GetDiscardHistory()),
+		Furiten:   g.IsFuriten(player),
+		Riichi:    riichiNames[p.GetRiichi()],
+		RedFives:  p.GetRedFives(),
+
+		MissingSuit: suitNames[p.GetMissingSuit()],
+		Won:         p.HasWon(),
+	}
+}
+
+func describeRoundResult(result *mahjong.RoundResult) *RoundResultView {
+	if result == nil {
+		return nil
+	}
+
+	wins := make([]WinView, len(result.Wins))
+	for i, w := range result.Wins {
+		tile := w.Context.WinningTile
+		wins[i] = WinView{
+			Player:    w.Context.Winner,
+			Discarder: w.Context.Discarder,
+			Tile:      tileName(&tile),
+			Concealed: tileCollectionNames(w.Hand.Concealed),
+			Exposed:   combinationNames(w.Hand.Exposed.Combinations(), false),
+			Score:     scoreItemNames(w.Score.Items),
+			Value:     w.Score.Value,
+			Total:     w.Score.Total,
+			Payments:  w.Payments,
+			Deposits:  w.Deposits,
+		}
+	}
+
+	return &RoundResultView{
+		Wins:     wins,
+		Abortive: result.Abortive,
+		Tenpai:   result.Tenpai,
+		Payments: result.Payments,
 	}
 }
@@ -9,14 +9,27 @@ type OtherPlayer struct {
 	Wind      string   `json:"wind"`
 	Exposed   []string `json:"exposed"`
 	Discarded []string `json:"discarded"`
+	History   []string `json:"discard_history"`
+	Riichi    string   `json:"riichi"`
+	// Suit the player has to get rid of and whether the player won and left the hand, in rulesets that play on after a win.
+	MissingSuit string `json:"missing_suit"`
+	Won         bool   `json:"won"`
 }
 
 type PlayerView struct {
 	Actions map[int]string `json:"actions"`
 
-	PrevalentWind    string `json:"prevalent_wind"`
-	DiscardingPlayer int    `json:"discarding_player"`
-	ActiveDiscard    string `json:"active_discard"`
+	PrevalentWind    string   `json:"prevalent_wind"`
+	Dealer           int      `json:"dealer"`
+	HandNumber       int      `json:"hand_number"`
+	HandsPlayed      int      `json:"hands_played"`
+	Repeats          int      `json:"repeats"`
+	WallSize         int      `json:"wall_size"`
+	DiscardingPlayer int      `json:"discarding_player"`
+	ActiveDiscard    string   `json:"active_discard"`
+	AddedKongTile    string   `json:"added_kong_tile"`
+	Deposits         int      `json:"riichi_deposits"`
+	Dora             []string `json:"dora_indicators"`
 
 	Score     int      `json:"score"`
 	Wind      string   `json:"wind"`
@@ -24,8 +37,17 @@ type PlayerView struct {
 	Concealed []string `json:"concealed"`
 	Exposed   []string `json:"exposed"`
 	Discarded []string `json:"discarded"`
+	History   []string `json:"discard_history"`
+	Furiten   bool     `json:"furiten"`
+	Riichi    string   `json:"riichi"`
+	RedFives  int      `json:"red_fives"`
+	// Suit the player has to get rid of and whether the player won and left the hand, in rulesets that play on after a win.
+	MissingSuit string `json:"missing_suit"`
+	Won         bool   `json:"won"`
 
 	OtherPlayers map[int]OtherPlayer `json:"other_players"`
+
+	LastRound *RoundResultView `json:"last_round"`
 }
 
 func ViewPlayer(game *mahjong.Game, playerIndex int) *PlayerView {
@@ -39,7 +61,7 @@ func ViewPlayer(game *mahjong.Game, playerIndex int) *PlayerView {
 	}
 
 	otherPlayers := make(map[int]OtherPlayer)
-	for _, p := range []int{0, 1, 2, 3} {
+	for p := 0; p < table.GetPlayerCount(); p++ {
 		if p == playerIndex {
 			continue
 		}
@@ -55,8 +77,16 @@ func ViewPlayer(game *mahjong.Game, playerIndex int) *PlayerView {
 
 	return &PlayerView{
 		PrevalentWind:    windNames[table.GetPrevalentWind()],
+		Dealer:           table.GetDealerIndex(),
+		HandNumber:       table.GetHandNumber(),
+		HandsPlayed:      table.GetHandsPlayed(),
+		Repeats:          table.GetRepeatCounter(),
+		WallSize:         table.GetWallSize(),
 		DiscardingPlayer: discardingPlayer,
 		ActiveDiscard:    activeDiscard,
+		AddedKongTile:    tileName(table.GetAddedKongTile()),
+		Deposits:         table.GetDeposits(),
+		Dora:             tileListNames(table.GetDoraIndicators()),
 
 		OtherPlayers: otherPlayers,
 
@@ -64,10 +94,19 @@ func ViewPlayer(game *mahjong.Game, playerIndex int) *PlayerView {
 		Wind:      windNames[player.GetWind()],
 		Received:  tileName(player.GetReceivedTile()),
 		Concealed: tileCollectionNames(player.GetConcealedTiles()),
-		Exposed:   combinationNames(player.GetExposedCombinations()),
+		Exposed:   combinationNames(player.GetExposedCombinations(), false),
 		Discarded: tileCollectionNames(player.GetDiscardedTiles()),
+		History:   discardHistoryNames(player.GetDiscardHistory()),
+		Furiten:   table.IsFuriten(playerIndex),
+		Riichi:    riichiNames[player.GetRiichi()],
+		RedFives:  player.GetRedFives(),
+
+		MissingSuit: suitNames[player.GetMissingSuit()],
+		Won:         player.HasWon(),
 
 		Actions: actionMap,
+
+		LastRound: describeRoundResult(table.GetLastRoundResult()),
 	}
 }
 
@@ -77,7 +116,12 @@ func describeOtherPlayer(table mahjong.Table, player int) OtherPlayer {
 	return OtherPlayer{
 		Score:     p.GetScore(),
 		Wind:      windNames[p.GetWind()],
-		Exposed:   combinationNames(p.GetExposedCombinations()),
+		Exposed:   combinationNames(p.GetExposedCombinations(), !table.GetRuleset().RevealConcealedKongs),
 		Discarded: tileCollectionNames(p.GetDiscardedTiles()),
+		History:   discardHistoryNames(p.GetDiscardHistory()),
+		Riichi:    riichiNames[p.GetRiichi()],
+
+		MissingSuit: suitNames[p.GetMissingSuit()],
+		Won:         p.HasWon(),
 	}
 }
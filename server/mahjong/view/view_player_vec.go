@@ -7,10 +7,17 @@ import (
 type PlayerVec struct {
 	Score            int       `json:"score"`
 	BonusTiles       []int     `json:"bonus_tiles"`
+	WallSize         int       `json:"wall_size"`
 	PrevalentWind    []int     `json:"prevalent_wind"`
+	HandNumber       int       `json:"hand_number"`
+	Repeats          int       `json:"repeats"`
+	Deposits         int       `json:"riichi_deposits"`
+	DoraIndicators   [][]int   `json:"dora_indicators"`
+	Dealer           []int     `json:"dealer"`
 	PlayerWind       []int     `json:"player_wind"`
 	DiscardingPlayer []int     `json:"discarding_player"`
 	ActiveDiscard    []int     `json:"active_discard"`
+	AddedKongTile    []int     `json:"added_kong_tile"`
 	Received         []int     `json:"received_tile"`
 	Concealed        [][]int   `json:"concealed_tiles"`
 	ExposedChows     [][][]int `json:"exposed_chows"`
@@ -18,6 +25,14 @@ type PlayerVec struct {
 	ExposedKongs     [][]int   `json:"exposed_kongs"`
 	HiddenKongs      [][]int   `json:"hidden_kongs"`
 	Discards         [][]int   `json:"discards"`
+	Furiten          int       `json:"furiten"`
+	RedFives         int       `json:"red_fives"`
+	// riichi status of the player itself, right, opposite and left player
+	Riichi []int `json:"riichi"`
+	// missing suit of the player itself, right, opposite and left player
+	MissingSuits []int `json:"missing_suits"`
+	// whether the player itself, right, opposite and left player won and left the hand
+	Won []int `json:"won"`
 	// player to the right
 	PlayerRScore        int       `json:"right_player_score"`
 	PlayerRBonusTiles   []int     `json:"right_player_bonus_tiles"`
@@ -58,59 +73,158 @@ func ViewPlayerVec(game *mahjong.Game, playerIndex int) *PlayerVec {
 		activeDiscard = tileToVec(table.GetActiveDiscard())
 	}
 
-	chows, pungs, kongs, hiddenKongs := exposedCombinations(player.GetExposedCombinations())
+	rules := table.GetRuleset()
+	// a hand holds one set for every three tiles dealt
+	maxSets := rules.HandSize / 3
 
-	playerRIndex := (playerIndex + 1) % 4
-	playerR := table.GetPlayerByIndex(playerRIndex)
-	rChows, rPungs, rKongs, rHiddenKongs := exposedCombinations(playerR.GetExposedCombinations())
-	playerOIndex := (playerIndex + 2) % 4
-	playerO := table.GetPlayerByIndex(playerOIndex)
-	oChows, oPungs, oKongs, oHiddenKongs := exposedCombinations(playerO.GetExposedCombinations())
-	playerLIndex := (playerIndex + 3) % 4
-	playerL := table.GetPlayerByIndex(playerLIndex)
-	lChows, lPungs, lKongs, lHiddenKongs := exposedCombinations(playerL.GetExposedCombinations())
+	chows, pungs, kongs, hiddenKongs := exposedCombinations(player.GetExposedCombinations(), maxSets, false)
+	hideConcealed := !rules.RevealConcealedKongs
+
+	playerR := describeOtherPlayerVec(table, seatAt(table, playerIndex, 1), maxSets, hideConcealed)
+	playerO := describeOtherPlayerVec(table, seatAt(table, playerIndex, 2), maxSets, hideConcealed)
+	playerL := describeOtherPlayerVec(table, seatAt(table, playerIndex, 3), maxSets, hideConcealed)
 
 	return &PlayerVec{
-		Score:               player.GetScore(),
-		BonusTiles:          bonusTiles(player.GetExposedCombinationCollection()),
-		PrevalentWind:       WindVectors[table.GetPrevalentWind()],
-		PlayerWind:          WindVectors[player.GetWind()],
-		DiscardingPlayer:    discardingPlayer,
-		ActiveDiscard:       activeDiscard,
-		Received:            tileToVec(player.GetReceivedTile()),
-		Concealed:           collectionToVec(player.GetConcealedTiles(), 13),
-		ExposedChows:        chows,
-		ExposedPungs:        pungs,
-		ExposedKongs:        kongs,
-		HiddenKongs:         hiddenKongs,
-		Discards:            collectionToVec(player.GetDiscardedTiles(), 40), // TODO: maybe this can be lower, determine worst case.
-		PlayerRScore:        playerR.GetScore(),
-		PlayerRBonusTiles:   bonusTiles(playerR.GetExposedCombinationCollection()),
-		PlayerRWind:         WindVectors[playerR.GetWind()],
-		PlayerRExposedChows: rChows,
-		PlayerRExposedPungs: rPungs,
-		PlayerRExposedKongs: rKongs,
-		PlayerRHiddenKongs:  rHiddenKongs,
-		PlayerRDiscards:     collectionToVec(playerR.GetDiscardedTiles(), 40),
-		PlayerOScore:        playerO.GetScore(),
-		PlayerOBonusTiles:   bonusTiles(playerO.GetExposedCombinationCollection()),
-		PlayerOWind:         WindVectors[playerO.GetWind()],
-		PlayerOExposedChows: oChows,
-		PlayerOExposedPungs: oPungs,
-		PlayerOExposedKongs: oKongs,
-		PlayerOHiddenKongs:  oHiddenKongs,
-		PlayerODiscards:     collectionToVec(playerO.GetDiscardedTiles(), 40),
-		PlayerLScore:        playerL.GetScore(),
-		PlayerLBonusTiles:   bonusTiles(playerL.GetExposedCombinationCollection()),
-		PlayerLWind:         WindVectors[playerL.GetWind()],
-		PlayerLExposedChows: lChows,
-		PlayerLExposedPungs: lPungs,
-		PlayerLExposedKongs: lKongs,
-		PlayerLHiddenKongs:  lHiddenKongs,
-		PlayerLDiscards:     collectionToVec(playerL.GetDiscardedTiles(), 40),
+		Score:            player.GetScore(),
+		BonusTiles:       bonusTiles(player.GetExposedCombinationCollection()),
+		WallSize:         table.GetWallSize(),
+		PrevalentWind:    WindVectors[table.GetPrevalentWind()],
+		HandNumber:       table.GetHandNumber(),
+		Repeats:          table.GetRepeatCounter(),
+		Deposits:         table.GetDeposits(),
+		DoraIndicators:   tileListToVec(table.GetDoraIndicators(), 5),
+		Dealer:           DealerVectors[relativePosition(table, playerIndex, table.GetDealerIndex())],
+		PlayerWind:       WindVectors[player.GetWind()],
+		DiscardingPlayer: discardingPlayer,
+		ActiveDiscard:    activeDiscard,
+		AddedKongTile:    tileToVec(table.GetAddedKongTile()),
+		Received:         tileToVec(player.GetReceivedTile()),
+		Concealed:        collectionToVec(player.GetConcealedTiles(), rules.HandSize+1), // players that won keep the winning tile in their hand
+		ExposedChows:     chows,
+		ExposedPungs:     pungs,
+		ExposedKongs:     kongs,
+		HiddenKongs:      hiddenKongs,
+		Discards:         collectionToVec(player.GetDiscardedTiles(), 40), // TODO: maybe this can be lower, determine worst case.
+		Furiten:          boolToInt(table.IsFuriten(playerIndex)),
+		RedFives:         player.GetRedFives(),
+		Riichi: []int{
+			int(player.GetRiichi()),
+			playerR.riichi,
+			playerO.riichi,
+			playerL.riichi,
+		},
+		MissingSuits: []int{
+			MissingSuitValues[player.GetMissingSuit()],
+			playerR.missingSuit,
+			playerO.missingSuit,
+			playerL.missingSuit,
+		},
+		Won: []int{
+			boolToInt(player.HasWon()),
+			playerR.won,
+			playerO.won,
+			playerL.won,
+		},
+		PlayerRScore:        playerR.score,
+		PlayerRBonusTiles:   playerR.bonusTiles,
+		PlayerRWind:         playerR.wind,
+		PlayerRExposedChows: playerR.chows,
+		PlayerRExposedPungs: playerR.pungs,
+		PlayerRExposedKongs: playerR.kongs,
+		PlayerRHiddenKongs:  playerR.hiddenKongs,
+		PlayerRDiscards:     playerR.discards,
+		PlayerOScore:        playerO.score,
+		PlayerOBonusTiles:   playerO.bonusTiles,
+		PlayerOWind:         playerO.wind,
+		PlayerOExposedChows: playerO.chows,
+		PlayerOExposedPungs: playerO.pungs,
+		PlayerOExposedKongs: playerO.kongs,
+		PlayerOHiddenKongs:  playerO.hiddenKongs,
+		PlayerODiscards:     playerO.discards,
+		PlayerLScore:        playerL.score,
+		PlayerLBonusTiles:   playerL.bonusTiles,
+		PlayerLWind:         playerL.wind,
+		PlayerLExposedChows: playerL.chows,
+		PlayerLExposedPungs: playerL.pungs,
+		PlayerLExposedKongs: playerL.kongs,
+		PlayerLHiddenKongs:  playerL.hiddenKongs,
+		PlayerLDiscards:     playerL.discards,
+	}
+}
+
+// The vectorized state of another player, as visible to the player.
+type otherPlayerVec struct {
+	score       int
+	bonusTiles  []int
+	wind        []int
+	chows       [][][]int
+	pungs       [][]int
+	kongs       [][]int
+	hiddenKongs [][]int
+	discards    [][]int
+	riichi      int
+	missingSuit int
+	won         int
+}
+
+// Describe the player at the given seat, or an empty seat if the seat is -1.
+func describeOtherPlayerVec(table mahjong.Table, playerIndex int, maxSets int, hideConcealed bool) otherPlayerVec {
+	if playerIndex == -1 {
+		chows, pungs, kongs, hiddenKongs := exposedCombinations(nil, maxSets, hideConcealed)
+		return otherPlayerVec{
+			score:       0,
+			bonusTiles:  make([]int, 9),
+			wind:        []int{0, 0, 0, 0},
+			chows:       chows,
+			pungs:       pungs,
+			kongs:       kongs,
+			hiddenKongs: hiddenKongs,
+			discards:    tileListToVec(nil, 40),
+			riichi:      0,
+			missingSuit: 0,
+			won:         0,
+		}
+	}
+
+	player := table.GetPlayerByIndex(playerIndex)
+	chows, pungs, kongs, hiddenKongs := exposedCombinations(player.GetExposedCombinations(), maxSets, hideConcealed)
+	return otherPlayerVec{
+		score:       player.GetScore(),
+		bonusTiles:  bonusTiles(player.GetExposedCombinationCollection()),
+		wind:        WindVectors[player.GetWind()],
+		chows:       chows,
+		pungs:       pungs,
+		kongs:       kongs,
+		hiddenKongs: hiddenKongs,
+		discards:    collectionToVec(player.GetDiscardedTiles(), 40),
+		riichi:      int(player.GetRiichi()),
+		missingSuit: MissingSuitValues[player.GetMissingSuit()],
+		won:         boolToInt(player.HasWon()),
 	}
 }
 
+// Position of a seat relative to the player: 0 for the player itself, 1 right, 2 opposite and 3 left.
+// With three players nobody sits opposite, so the player after the right player is on the left.
+func relativePosition(table mahjong.Table, playerIndex int, seat int) int {
+	n := table.GetPlayerCount()
+	offset := (seat - playerIndex + n) % n
+	if offset != 0 && offset == n-1 {
+		return 3
+	}
+	return offset
+}
+
+// The seat at the position relative to the player, or -1 if nobody sits there.
+func seatAt(table mahjong.Table, playerIndex int, position int) int {
+	n := table.GetPlayerCount()
+	for seat := 0; seat < n; seat++ {
+		if relativePosition(table, playerIndex, seat) == position {
+			return seat
+		}
+	}
+	return -1
+}
+
 var WindVectors = map[mahjong.Wind][]int{
 	mahjong.East:  {1, 0, 0, 0},
 	mahjong.South: {0, 1, 0, 0},
@@ -118,6 +232,26 @@ var WindVectors = map[mahjong.Wind][]int{
 	mahjong.North: {0, 0, 0, 1},
 }
 
+// Missing suit by its first tile: none, bamboo, circles and characters.
+var MissingSuitValues = map[mahjong.Tile]int{
+	0:                   0,
+	mahjong.Bamboo1:     1,
+	mahjong.Circles1:    2,
+	mahjong.Characters1: 3,
+}
+
+// Vector for a tile that is known to be there, but whose identity is not visible to the player.
+var HiddenTileVector = []int{-1, -1, -1}
+
+// Dealer seat relative to the player: the player itself, right, opposite and left.
+var DealerVectors = map[int][]int{
+	0: {1, 0, 0, 0},
+	1: {0, 1, 0, 0},
+	2: {0, 0, 1, 0},
+	3: {0, 0, 0, 1},
+}
+
+// Discarding player relative to the player: right, opposite and left.
 var DiscardingPlayerVectors = map[int][]int{
 	0: {0, 0, 0},
 	1: {1, 0, 0},
@@ -130,10 +264,6 @@ func collectionToVec(coll *mahjong.TileCollection, maxLen int) [][]int {
 	tileIndex := 0
 
 	for _, tileCount := range coll.OrderedCounts() {
-		if tileCount.Tile.IsBonusTile() {
-			// we know the order, so break early on first bonus tile occurrence
-			break
-		}
 		tileVec := tileToVec(&tileCount.Tile)
 		for i := tileCount.Count; i > 0; i-- {
 			vector[tileIndex] = tileVec
@@ -147,14 +277,27 @@ func collectionToVec(coll *mahjong.TileCollection, maxLen int) [][]int {
 	return vector
 }
 
-func exposedCombinations(combinations []mahjong.Combination) ([][][]int, [][]int, [][]int, [][]int) {
-	chowVector := make([][][]int, 4)
+func tileListToVec(tiles []mahjong.Tile, maxLen int) [][]int {
+	vector := make([][]int, maxLen)
+	for i := 0; i < maxLen; i++ {
+		if i < len(tiles) {
+			vector[i] = tileToVec(&tiles[i])
+		} else {
+			vector[i] = tileToVec(nil)
+		}
+	}
+	return vector
+}
+
+// Vectorize the combinations per type, each padded to maxSets. If hideConcealed is set, concealed kongs are only marked as present with HiddenTileVector.
+func exposedCombinations(combinations []mahjong.Combination, maxSets int, hideConcealed bool) ([][][]int, [][]int, [][]int, [][]int) {
+	chowVector := make([][][]int, maxSets)
 	chowIndex := 0
-	pungVector := make([][]int, 4)
+	pungVector := make([][]int, maxSets)
 	pungIndex := 0
-	kongVector := make([][]int, 4)
+	kongVector := make([][]int, maxSets)
 	kongIndex := 0
-	hiddenKongVector := make([][]int, 4)
+	hiddenKongVector := make([][]int, maxSets)
 	hiddenKongIndex := 0
 	for _, combination := range combinations {
 		switch c := combination.(type) {
@@ -170,7 +313,11 @@ func exposedCombinations(combinations []mahjong.Combination) ([][][]int, [][]int
 			pungIndex++
 		case mahjong.Kong:
 			if c.Concealed == true {
-				hiddenKongVector[hiddenKongIndex] = tileToVec(&c.Tile)
+				if hideConcealed {
+					hiddenKongVector[hiddenKongIndex] = HiddenTileVector
+				} else {
+					hiddenKongVector[hiddenKongIndex] = tileToVec(&c.Tile)
+				}
 				hiddenKongIndex++
 			} else {
 				kongVector[kongIndex] = tileToVec(&c.Tile)
@@ -178,7 +325,7 @@ func exposedCombinations(combinations []mahjong.Combination) ([][][]int, [][]int
 			}
 		}
 	}
-	for i := 0; i < 4; i++ {
+	for i := 0; i < maxSets; i++ {
 		if i >= chowIndex {
 			chowVector[i] = [][]int{tileToVec(nil), tileToVec(nil), tileToVec(nil)}
 		}
@@ -205,6 +352,7 @@ func bonusTiles(t *mahjong.CombinationCollection) []int {
 		numBonus(t, mahjong.SeasonSummer),
 		numBonus(t, mahjong.SeasonAutumn),
 		numBonus(t, mahjong.SeasonWinter),
+		t.Count(mahjong.BonusTile{Tile: mahjong.NorthWind}),
 	}
 }
 
@@ -216,16 +364,13 @@ func numBonus(t *mahjong.CombinationCollection, tile mahjong.Tile) int {
 }
 
 func discardingPlayerVec(table mahjong.Table, playerIndex int) []int {
-	return DiscardingPlayerVectors[((table.GetActivePlayerIndex()-playerIndex)+4)%4]
+	return DiscardingPlayerVectors[relativePosition(table, playerIndex, table.GetActivePlayerIndex())]
 }
 
 func tileToVec(t *mahjong.Tile) []int {
 	if t == nil {
 		return []int{0, 0, 0}
 	}
-	if t.IsBonusTile() {
-		panic("cannot express bonus tile as tile vector")
-	}
 	tile := int(*t)
 	group := 0
 	tpe := 0
@@ -242,5 +387,21 @@ func tileToVec(t *mahjong.Tile) []int {
 		group = 3
 		tpe = tile % 10
 	}
+	// flowers and seasons are only part of the hand in rulesets that keep them there
+	if t.IsBonusTile() {
+		group = 4
+		tpe = tile/10 - 5
+		nr = tile % 10
+	}
+	if t.IsJoker() {
+		group = 5
+	}
 	return []int{group, tpe, nr}
 }
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package view
+
+import (
+	"fmt"
+	"github.com/roelofruis/mahjong-learn/mahjong"
+	"github.com/roelofruis/mahjong-learn/state"
+	"math/rand"
+	"testing"
+)
+
+func TestConcealedVecTaiwanese(t *testing.T) {
+	game, _ := mahjong.NewSeededGame(mahjong.TaiwaneseRuleset(), 42, &state.ProductionTransitioner{IntermediateTransitionLimit: 10})
+
+	for s := 0; s < game.Table.GetPlayerCount(); s++ {
+		concealed := ViewPlayerVec(game, s).Concealed
+		if len(concealed) != 17 {
+			t.Fatalf("expected room for a 16 tile hand and a winning tile, got [%d]", len(concealed))
+		}
+		checkConcealedVec(t, game.Table.GetPlayerByIndex(s).GetConcealedTiles(), concealed)
+	}
+}
+
+func TestConcealedVecSichuanWinner(t *testing.T) {
+	choices := rand.New(rand.NewSource(7))
+	for seed := int64(0); seed < 100; seed++ {
+		game, _ := mahjong.NewSeededGame(mahjong.SichuanRuleset(), seed, &state.ProductionTransitioner{IntermediateTransitionLimit: 10})
+		for !game.StateMachine.HasTerminated() {
+			for s := 0; s < game.Table.GetPlayerCount(); s++ {
+				if game.Table.GetPlayerByIndex(s).HasWon() {
+					checkConcealedVec(t, game.Table.GetPlayerByIndex(s).GetConcealedTiles(), ViewPlayerVec(game, s).Concealed)
+					return
+				}
+			}
+
+			selectedActions := make(map[int]int)
+			for player, a := range game.StateMachine.AvailableActions() {
+				selectedActions[player] = choices.Intn(len(a))
+			}
+			if err := game.Transition(selectedActions); err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+		}
+	}
+	t.Fatalf("no player won and stayed at the table")
+}
+
+// Every concealed tile is encoded once, and the rest of the vector is empty.
+func checkConcealedVec(t *testing.T, tiles *mahjong.TileCollection, vec [][]int) {
+	encoded := make(map[string]int)
+	for _, tc := range tiles.OrderedCounts() {
+		encoded[fmt.Sprint(tileToVec(&tc.Tile))] += int(tc.Count)
+	}
+	for _, v := range vec {
+		encoded[fmt.Sprint(v)]--
+	}
+	empty := fmt.Sprint(tileToVec(nil))
+	for v, n := range encoded {
+		if v != empty && n != 0 {
+			t.Errorf("expected every concealed tile in the vector once, tile %s is off by [%d]", v, n)
+		}
+	}
+}
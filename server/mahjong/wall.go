@@ -0,0 +1,182 @@
+package mahjong
+
+import (
+	"math/rand"
+)
+
+const deadWallSize = 14
+
+// Wall is the ordered sequence of tiles the players draw from.
+// Regular draws come from the front of the live wall, replacement tiles for kongs and bonus tiles come from the dead wall at the back.
+type Wall struct {
+	// Tiles that can be drawn, in order.
+	live []wallTile
+	// Tiles that are set aside for replacement draws, in order.
+	// After every replacement draw the last tile of the live wall is added, so the dead wall keeps its size as long as the live wall lasts.
+	dead []wallTile
+	// Tiles taken from the dead wall to serve as dora indicators, which are never drawn.
+	indicators []wallTile
+}
+
+// A tile in the wall. Red fives play as regular fives, but are worth extra in some rulesets.
+type wallTile struct {
+	tile Tile
+	red  bool
+}
+
+// randomSource is a splitmix64 generator. Unlike the sources of math/rand its state is a single number,
+// so the random sequence of a game can be saved and copied to continue exactly where it was.
+type randomSource struct {
+	state uint64
+}
+
+func newRandomSource(seed int64) *randomSource {
+	return &randomSource{state: uint64(seed)}
+}
+
+func (s *randomSource) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+func (s *randomSource) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *randomSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Build a wall from the given set of tiles, shuffled by rng, in which redFives fives of each suit are red.
+func newWall(set *TileCollection, redFives int, rng *rand.Rand) *Wall {
+	list := set.list()
+	rng.Shuffle(len(list), func(i, j int) {
+		list[i], list[j] = list[j], list[i]
+	})
+
+	tiles := make([]wallTile, len(list))
+	redLeft := map[Tile]int{Bamboo5: redFives, Circles5: redFives, Characters5: redFives}
+	for i, tile := range list {
+		red := redLeft[tile] > 0
+		if red {
+			redLeft[tile]--
+		}
+		tiles[i] = wallTile{tile: tile, red: red}
+	}
+
+	split := len(tiles) - deadWallSize
+	if split < 0 {
+		split = 0
+	}
+
+	return &Wall{
+		live:       tiles[:split:split],
+		dead:       tiles[split:],
+		indicators: make([]wallTile, 0),
+	}
+}
+
+// Getters
+
+// Total number of tiles in the wall, including the dead wall and the indicators.
+func (w *Wall) Size() int {
+	return len(w.live) + len(w.dead) + len(w.indicators)
+}
+
+// Number of tiles that can still be drawn before the round ends.
+func (w *Wall) LiveSize() int {
+	return len(w.live)
+}
+
+func (w *Wall) DeadSize() int {
+	return len(w.dead)
+}
+
+// Whether the live wall is used up.
+func (w *Wall) IsExhausted() bool {
+	return len(w.live) == 0
+}
+
+// All remaining tiles, both live and dead, without their order.
+func (w *Wall) Tiles() *TileCollection {
+	tiles := newEmptyTileCollection()
+	for _, t := range w.live {
+		tiles.add(t.tile)
+	}
+	for _, t := range w.dead {
+		tiles.add(t.tile)
+	}
+	for _, t := range w.indicators {
+		tiles.add(t.tile)
+	}
+	return tiles
+}
+
+// The indicator tiles that were set aside, in order.
+func (w *Wall) Indicators() []Tile {
+	indicators := make([]Tile, len(w.indicators))
+	for i, t := range w.indicators {
+		indicators[i] = t.tile
+	}
+	return indicators
+}
+
+// All tiles in the order they are in the wall: the live wall, the dead wall and the indicators.
+func (w *Wall) order() []Tile {
+	tiles := make([]Tile, 0, w.Size())
+	for _, part := range [][]wallTile{w.live, w.dead, w.indicators} {
+		for _, t := range part {
+			tiles = append(tiles, t.tile)
+		}
+	}
+	return tiles
+}
+
+// State Modifiers
+
+// Draw the next tile from the live wall, and whether it is a red five.
+// Only when the live wall is used up a tile is taken from the dead wall instead.
+func (w *Wall) draw() (Tile, bool) {
+	if len(w.live) == 0 {
+		t := w.dead[0]
+		w.dead = w.dead[1:]
+		return t.tile, t.red
+	}
+
+	t := w.live[0]
+	w.live = w.live[1:]
+
+	return t.tile, t.red
+}
+
+// Draw a replacement tile from the dead wall and move the last live tile to the dead wall.
+// Only when the dead wall is used up a tile is taken from the live wall instead.
+func (w *Wall) drawReplacement() (Tile, bool) {
+	if len(w.dead) == 0 {
+		return w.draw()
+	}
+
+	t := w.dead[0]
+	w.dead = w.dead[1:]
+
+	if len(w.live) > 0 {
+		last := len(w.live) - 1
+		w.dead = append(w.dead, w.live[last])
+		w.live = w.live[:last]
+	}
+
+	return t.tile, t.red
+}
+
+// Take n tiles from the back of the dead wall to serve as indicators.
+// The indicators remain part of the dead wall, so fewer tiles are left for replacement draws.
+func (w *Wall) setAsideIndicators(n int) {
+	for i := 0; i < n && len(w.dead) > 0; i++ {
+		last := len(w.dead) - 1
+		w.indicators = append(w.indicators, w.dead[last])
+		w.dead = w.dead[:last]
+	}
+}
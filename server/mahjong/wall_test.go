@@ -0,0 +1,71 @@
+package mahjong
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestWallReplacementDraws(t *testing.T) {
+	wall := newWall(newMahjongSet(), 0, rand.New(rand.NewSource(0)))
+
+	if wall.Size() != 144 || wall.DeadSize() != deadWallSize {
+		t.Fatalf("expected a wall of [144] tiles with a dead wall of [%d], got [%d] and [%d]", deadWallSize, wall.Size(), wall.DeadSize())
+	}
+
+	lastLive := wall.live[len(wall.live)-1]
+	firstDead := wall.dead[0].tile
+
+	if tile, _ := wall.drawReplacement(); tile != firstDead {
+		t.Errorf("expected replacement [%d] to come from the dead wall, got [%d]", firstDead, tile)
+	}
+	if wall.DeadSize() != deadWallSize || wall.dead[deadWallSize-1] != lastLive {
+		t.Errorf("expected the last live tile to move to the dead wall")
+	}
+
+	for !wall.IsExhausted() {
+		wall.draw()
+	}
+	if wall.Size() != deadWallSize {
+		t.Errorf("expected the live wall to end at the dead wall, [%d] tiles remain", wall.Size())
+	}
+}
+
+func TestWallIndicatorsAndRedFives(t *testing.T) {
+	wall := RiichiRuleset().newWall(rand.New(rand.NewSource(0)))
+
+	if wall.Size() != 136 || len(wall.Indicators()) != 10 || wall.DeadSize() != deadWallSize-10 {
+		t.Fatalf("expected [136] tiles with [10] indicators set aside from the dead wall, got [%d], [%d] and [%d]", wall.Size(), len(wall.Indicators()), wall.DeadSize())
+	}
+
+	red := 0
+	for _, w := range append(append(wall.live, wall.dead...), wall.indicators...) {
+		if w.red {
+			red++
+			if w.tile%10 != 5 || !w.tile.IsSuit() {
+				t.Errorf("expected only fives to be red, got [%d]", w.tile)
+			}
+		}
+	}
+	if red != 3 {
+		t.Errorf("expected one red five per suit, got [%d]", red)
+	}
+}
+
+func TestTileSetSize(t *testing.T) {
+	cases := []struct {
+		set  TileSet
+		size int
+	}{
+		{set: TileSet{Honors: true, BonusTiles: true}, size: 144},
+		{set: TileSet{Honors: true}, size: 136},
+		{set: TileSet{}, size: 108},
+		{set: TileSet{Honors: true, Excluded: []Tile{Characters2, Characters3}}, size: 128},
+		{set: SanmaRuleset().Tiles, size: 108},
+	}
+
+	for _, c := range cases {
+		if size := c.set.Size(); size != c.size {
+			t.Errorf("expected tile set %+v to have [%d] tiles, got [%d]", c.set, c.size, size)
+		}
+	}
+}
@@ -2,21 +2,29 @@ package main
 
 import (
 	"github.com/gorilla/mux"
+	"github.com/roelofruis/mahjong-learn/mahjong"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
-	"time"
 )
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
-
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000"
 	}
 
+	cardFile := os.Getenv("CARD")
+	if cardFile == "" {
+		cardFile = "mahjong/cards/sample.json"
+	}
+	card, err := mahjong.LoadCard(cardFile)
+	if err != nil {
+		log.Printf("american ruleset unavailable: %s", err.Error())
+	} else {
+		mahjong.RegisterRuleset("american", func() mahjong.Ruleset { return mahjong.AmericanRuleset(card) })
+	}
+
 	server := &Server{
 		Host:   "localhost",
 		Port:   port,
@@ -29,7 +37,7 @@ func main() {
 
 	log.Printf("mahjong API")
 	log.Printf("server starting on %s", server.GetDomain(true))
-	err := http.ListenAndServe(server.GetDomain(false), server)
+	err = http.ListenAndServe(server.GetDomain(false), server)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -37,8 +37,12 @@ type RequestHandler func(*http.Request) *Response
 func (s *Server) Routes() {
 	s.Router.HandleFunc("/", s.asJsonResponse(s.handleIndex))
 	s.Router.HandleFunc("/new", s.asJsonResponse(s.handleNew))
+	s.Router.HandleFunc("/restore", s.asJsonResponse(s.handleRestore)).Methods("POST")
 	s.Router.HandleFunc("/game/{id:[0-9]+}", s.asJsonResponse(s.withGame(s.handleDisplayGame))).Methods("GET")
 	s.Router.HandleFunc("/game/{id:[0-9]+}", s.asJsonResponse(s.withValidForm(s.withGame(s.handleActions)))).Methods("POST")
+	s.Router.HandleFunc("/game/{id:[0-9]+}/undo", s.asJsonResponse(s.withValidForm(s.withGame(s.handleUndo)))).Methods("POST")
+	s.Router.HandleFunc("/game/{id:[0-9]+}/snapshot", s.asJsonResponse(s.withGame(s.handleSnapshot))).Methods("GET")
+	s.Router.HandleFunc("/game/{id:[0-9]+}/log", s.asJsonResponse(s.withGame(s.handleLog))).Methods("GET")
 	s.Router.HandleFunc("/game/{id:[0-9]+}/player/{player:[0-9]+}", s.asJsonResponse(s.withGame(s.handleDisplayPlayer))).Methods("GET")
 	s.Router.NotFoundHandler = s.asJsonResponse(s.notFoundHandler)
 }
@@ -12,6 +12,16 @@ type StateMachine struct {
 	state *State
 
 	transitioner Transitioner
+
+	// Previous states, oldest first, with a function that puts back the data they act on.
+	history      []checkpoint
+	historyLimit int
+	save         func() (restore func())
+}
+
+type checkpoint struct {
+	state   *State
+	restore func()
 }
 
 // Name of the current state the state machine is in
@@ -19,6 +29,11 @@ func (s *StateMachine) StateName() string {
 	return s.state.name
 }
 
+// The transitioner that performs the transitions of the state machine.
+func (s *StateMachine) Transitioner() Transitioner {
+	return s.transitioner
+}
+
 // Whether the state machine is in a terminal state and no more actions can be performed.
 // If this returns true, calling Transition is a no-op.
 func (s *StateMachine) HasTerminated() bool {
@@ -31,11 +46,6 @@ func (s *StateMachine) AvailableActions() map[int][]Action {
 		return make(map[int][]Action)
 	}
 
-	for player, a := range s.state.actions {
-		sort.Sort(byActionOrder(a))
-		s.state.actions[player] = a
-	}
-
 	return s.state.actions
 }
 
@@ -47,7 +57,58 @@ func (s *StateMachine) Transition(selectedActions map[int]int) error {
 		return nil
 	}
 
-	return s.transitioner.Transition(s, selectedActions)
+	if s.historyLimit == 0 {
+		return s.transitioner.Transition(s, selectedActions)
+	}
+
+	previous := checkpoint{state: s.state, restore: s.save()}
+	err := s.transitioner.Transition(s, selectedActions)
+	if err != nil {
+		return err
+	}
+
+	s.history = append(s.history, previous)
+	if len(s.history) > s.historyLimit {
+		s.history = s.history[1:]
+	}
+	return nil
+}
+
+// Keep up to limit previous states, so transitions can be undone. The states act on data outside of the machine,
+// so save is called before every transition and returns a function that puts the data back as it was.
+func (s *StateMachine) KeepHistory(limit int, save func() (restore func())) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.historyLimit = limit
+	s.save = save
+	if len(s.history) > limit {
+		s.history = s.history[len(s.history)-limit:]
+	}
+}
+
+// Number of transitions that can be undone.
+func (s *StateMachine) HistorySize() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.history)
+}
+
+// Undo the last transition, returning to the state in which the actions were chosen.
+func (s *StateMachine) Undo() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.history) == 0 {
+		return NoHistoryError{}
+	}
+
+	previous := s.history[len(s.history)-1]
+	s.history = s.history[:len(s.history)-1]
+	previous.restore()
+	s.state = previous.state
+	return nil
 }
 
 func NewStateMachine(initialState *State, transitioner Transitioner) *StateMachine {
@@ -70,13 +131,23 @@ type State struct {
 	transition func(map[int]Action) (*State, error)
 }
 
+// Name of the state, which identifies it among the states of a game.
+func (s *State) Name() string {
+	return s.name
+}
+
 type Action interface {
 	// Defines an order for the actions returned.
 	// Needs to be unique among simultaneous action options to guarantee a stable sorting.
 	ActionOrder() int
 }
 
+// The actions are sorted by their order, so the selected action indices do not depend on the order in which they were generated.
 func NewState(name string, actions map[int][]Action, transition func(map[int]Action) (*State, error)) *State {
+	for _, a := range actions {
+		sort.Sort(byActionOrder(a))
+	}
+
 	return &State{
 		name:       name,
 		actions:    actions,
@@ -123,6 +194,12 @@ func (e TooManyIntermediateStatesError) Error() string {
 	return fmt.Sprintf("transitioning to next actionable state took more than [%d] steps", e.transitionLimit)
 }
 
+type NoHistoryError struct{}
+
+func (e NoHistoryError) Error() string {
+	return "there is no transition to undo"
+}
+
 type TransitionLogicError struct {
 	Err error
 }
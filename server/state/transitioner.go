@@ -12,12 +12,19 @@ type Transitioner interface {
 	// TooManyIntermediateStatesError in case the chain of intermediate states became too long.
 	// TransitionLogicError in case executing the transition logic returned an error.
 	Transition(machine *StateMachine, selectedActions map[int]int) error
+
+	// A new transitioner with the same configuration, which shares no state with this one.
+	Clone() Transitioner
 }
 
 type ProductionTransitioner struct {
 	IntermediateTransitionLimit int
 }
 
+func (t *ProductionTransitioner) Clone() Transitioner {
+	return &ProductionTransitioner{IntermediateTransitionLimit: t.IntermediateTransitionLimit}
+}
+
 func (t *ProductionTransitioner) Transition(m *StateMachine, selectedActions map[int]int) error {
 	playerActions := make(map[int]Action)
 
@@ -69,6 +76,11 @@ type DebugTransitioner struct {
 	LastSelection map[int]int
 }
 
+// The clone starts counting its actions from zero.
+func (t *DebugTransitioner) Clone() Transitioner {
+	return &DebugTransitioner{IntermediateTransitionLimit: t.IntermediateTransitionLimit, ActionLimit: t.ActionLimit}
+}
+
 func (t *DebugTransitioner) Transition(m *StateMachine, selectedActions map[int]int) error {
 	t.actionsPerformed++
 	if t.actionsPerformed > t.ActionLimit {
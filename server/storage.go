@@ -8,6 +8,9 @@ import (
 	"sync/atomic"
 )
 
+// Number of transitions that can be undone in a stored game. Each one keeps a copy of the table in memory.
+const historyLimit = 100
+
 func NewGameStorage() *GameStorage {
 	return &GameStorage{
 		gamesLock: sync.RWMutex{},
@@ -37,13 +40,14 @@ func (s *GameStorage) Get(id uint64) (*mahjong.Game, error) {
 	return g, nil
 }
 
-func (s *GameStorage) StartNew() (uint64, error) {
+func (s *GameStorage) StartNew(rules mahjong.Ruleset, seed int64) (uint64, error) {
 	id := atomic.AddUint64(s.lastIndex, 1)
 
-	m, err := mahjong.NewGame(&state.ProductionTransitioner{IntermediateTransitionLimit: 10})
+	m, err := mahjong.NewSeededGame(rules, seed, &state.ProductionTransitioner{IntermediateTransitionLimit: 10})
 	if err != nil {
 		return id, err
 	}
+	m.KeepHistory(historyLimit)
 
 	s.gamesLock.Lock()
 	s.games[id] = m
@@ -51,3 +55,15 @@ func (s *GameStorage) StartNew() (uint64, error) {
 
 	return id, nil
 }
+
+// Store a game that was started elsewhere, such as a restored one, under a new id.
+func (s *GameStorage) Add(game *mahjong.Game) uint64 {
+	id := atomic.AddUint64(s.lastIndex, 1)
+	game.KeepHistory(historyLimit)
+
+	s.gamesLock.Lock()
+	s.games[id] = game
+	s.gamesLock.Unlock()
+
+	return id
+}